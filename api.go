@@ -0,0 +1,116 @@
+package ck
+
+import (
+	"context"
+	"io"
+
+	"github.com/mswift42/goquery"
+)
+
+// ParseRecipes returns the recipes listed on a chefkoch search result
+// page.
+func ParseRecipes(r io.Reader) ([]*Recipe, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return allRecipes(doc), nil
+}
+
+// ParseRecipeDetail returns the recipe of a chefkoch recipe page.
+func ParseRecipeDetail(r io.Reader) (*RecipeDetail, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	rd := (&RecipeDetailDocument{doc}).newRecipeDetail()
+	if rd.Title == "" {
+		return nil, &ParseError{"", "no recipe found"}
+	}
+	return rd, nil
+}
+
+// fetchRecipeDetail fetches and parses the recipe page at recurl. The ID
+// is taken from recurl when the page has no canonical link.
+func fetchRecipeDetail(ctx context.Context, recurl string) (*RecipeDetail, error) {
+	doc, err := fetchDocument(ctx, recurl, DetailTTL)
+	if err != nil {
+		return nil, err
+	}
+	rd := (&RecipeDetailDocument{doc}).newRecipeDetail()
+	if rd.Title == "" {
+		return nil, &ParseError{recurl, "no recipe found"}
+	}
+	if rd.ID == "" {
+		rd.ID = recipeID(recurl)
+	}
+	return rd, nil
+}
+
+// SearchOptions are the optional parameters of Search, matching the
+// parameters of /search. Page starts at 1; zero values are ignored.
+type SearchOptions struct {
+	Page       int
+	Difficulty []string
+	MaxTime    int
+	MinRating  float64
+	MinVotes   int
+}
+
+func (o *SearchOptions) filter() (*searchFilter, error) {
+	f := &searchFilter{}
+	if o == nil {
+		return f, nil
+	}
+	if len(o.Difficulty) > 0 {
+		if err := f.setDifficulty(o.Difficulty); err != nil {
+			return nil, err
+		}
+	}
+	if o.MaxTime < 0 {
+		return nil, &BadRequestError{"maxtime must be a positive number of minutes"}
+	}
+	if o.MinRating < 0 || o.MinRating > 5 {
+		return nil, &BadRequestError{"minrating must be between 0 and 5"}
+	}
+	if o.MinVotes < 0 {
+		return nil, &BadRequestError{"minvotes must be a non-negative number"}
+	}
+	f.maxTime, f.minRating, f.minVotes = o.MaxTime, o.MinRating, o.MinVotes
+	return f, nil
+}
+
+// Match reports whether r passes the filters of o.
+func (o *SearchOptions) Match(r *Recipe) bool {
+	f, err := o.filter()
+	return err == nil && f.match(r)
+}
+
+// Search runs a chefkoch search for query like /search does, using
+// DefaultFetcher and the page cache.
+func Search(ctx context.Context, query string, opts *SearchOptions) (*SearchResponse, error) {
+	f, err := opts.filter()
+	if err != nil {
+		return nil, err
+	}
+	var cursor searchCursor
+	if opts != nil && opts.Page > 1 {
+		cursor.Offset = pageOffset(opts.Page)
+	}
+	return searchRecipes(ctx, query, cursor, f)
+}
+
+// Detail fetches the recipe with the given chefkoch ID or URL and scales
+// it to servings unless servings is 0.
+func Detail(ctx context.Context, idOrURL string, servings int) (*RecipeDetail, error) {
+	recurl, err := recipeURL(idOrURL)
+	if err != nil {
+		return nil, err
+	}
+	rd, err := fetchRecipeDetail(ctx, recurl)
+	if err != nil {
+		return nil, err
+	}
+	rd.Scale(servings)
+	return rd, nil
+}
@@ -0,0 +1,71 @@
+package ck
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseRecipes(t *testing.T) {
+	f, err := os.Open("testhtml/bohnen.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	recipes, err := ParseRecipes(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipes) != 30 {
+		t.Fatalf("Expected 30 recipes, got: %d", len(recipes))
+	}
+	opts := &SearchOptions{MinRating: 4.5, MinVotes: 100}
+	matched := 0
+	for _, i := range recipes {
+		if opts.Match(i) {
+			matched++
+		}
+	}
+	if matched != 1 {
+		t.Errorf("Expected 1 recipe with a rating of 4.5 and 100 votes, got: %d", matched)
+	}
+	if (&SearchOptions{Difficulty: []string{"schwer"}}).Match(recipes[0]) {
+		t.Error("Expected invalid options to match nothing")
+	}
+}
+
+func TestParseRecipeDetail(t *testing.T) {
+	f, err := os.Open("testhtml/schupfnudel.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rd, err := ParseRecipeDetail(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rd.Title != schupfnudel.title || rd.ID != "1171381223217983" {
+		t.Errorf("Expected %q, got: %q (%s)", schupfnudel.title, rd.Title, rd.ID)
+	}
+	_, err = ParseRecipeDetail(strings.NewReader("<html><body>Wartung</body></html>"))
+	if _, ok := err.(*ParseError); !ok {
+		t.Errorf("Expected a *ParseError for a page without recipe, got: %v", err)
+	}
+}
+
+func TestSearchOptions(t *testing.T) {
+	for _, i := range []*SearchOptions{
+		{Difficulty: []string{"schwer"}},
+		{MaxTime: -1},
+		{MinRating: 6},
+		{MinVotes: -1},
+	} {
+		if _, err := Search(context.Background(), "bohnen", i); err == nil {
+			t.Errorf("Expected %+v to be rejected", i)
+		}
+	}
+	if _, err := Detail(context.Background(), "http://localhost/", 0); err == nil {
+		t.Error("Expected Detail to reject non-chefkoch URLs")
+	}
+}
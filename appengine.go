@@ -0,0 +1,17 @@
+//go:build appengine
+// +build appengine
+
+package ck
+
+import (
+	"log"
+	"net/http"
+)
+
+// The App Engine go1 runtime serves http.DefaultServeMux.
+func init() {
+	http.Handle("/", NewServeMux("*"))
+	if err := ConfigureFromEnv(); err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,153 @@
+package ck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// BulkSearchWorkers is the number of upstream result pages fetched
+// concurrently by /searchpages.
+var BulkSearchWorkers = 4
+
+// BulkSearchMaxPages limits the pages parameter of /searchpages.
+var BulkSearchMaxPages = 20
+
+// BulkSearchResponse holds the recipes of several consecutive result
+// pages in upstream order, without duplicates. Pages that could not be
+// fetched are listed in Errors and missing from Results.
+type BulkSearchResponse struct {
+	Version int          `json:"version"`
+	Query   string       `json:"query"`
+	Total   int          `json:"total"`
+	Pages   int          `json:"pages"`
+	Results []*Recipe    `json:"results"`
+	Errors  []*PageError `json:"errors,omitempty"`
+}
+
+// PageError reports a failed result page, counting from 1.
+type PageError struct {
+	Page  int    `json:"page"`
+	Error string `json:"error"`
+}
+
+type pageResult struct {
+	recipes []*Recipe
+	total   int
+	err     error
+}
+
+// fetchPages fetches count result pages for query starting at the upstream
+// offset with at most workers concurrent requests. The results are indexed
+// by page.
+func fetchPages(ctx context.Context, query string, offset, count, workers int) []*pageResult {
+	results := make([]*pageResult, count)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range jobs {
+				url := queryUrl(query, strconv.Itoa(offset+page*SearchPageSize))
+				doc, err := fetchDocument(ctx, url, SearchTTL)
+				if err != nil {
+					results[page] = &pageResult{err: err}
+					continue
+				}
+				results[page] = &pageResult{recipes: allRecipes(doc),
+					total: searchTotal(doc)}
+			}
+		}()
+	}
+	for page := 0; page < count; page++ {
+		jobs <- page
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func bulkSearch(ctx context.Context, query string, cursor searchCursor, pages int, f *searchFilter) *BulkSearchResponse {
+	workers := BulkSearchWorkers
+	if workers > pages {
+		workers = pages
+	}
+	results := fetchPages(ctx, query, cursor.Offset, pages, workers)
+	resp := mergePages(results, cursor.Offset/SearchPageSize+1, f)
+	resp.Query = query
+	return resp
+}
+
+// mergePages joins the pages returned by fetchPages, keeping the upstream
+// order, dropping recipes already seen on an earlier page and applying f.
+// first is the number of the first page.
+func mergePages(results []*pageResult, first int, f *searchFilter) *BulkSearchResponse {
+	resp := &BulkSearchResponse{Version: SearchResponseVersion,
+		Pages: len(results), Results: []*Recipe{}}
+	seen := make(map[string]bool)
+	for ind, i := range results {
+		if i.err != nil {
+			resp.Errors = append(resp.Errors, &PageError{first + ind, i.err.Error()})
+			continue
+		}
+		if resp.Total == 0 {
+			resp.Total = i.total
+		}
+		for _, r := range i.recipes {
+			if seen[r.Url] || !f.match(r) {
+				continue
+			}
+			seen[r.Url] = true
+			resp.Results = append(resp.Results, r)
+		}
+	}
+	return resp
+}
+
+func bulkSearchPages(r *http.Request) (int, error) {
+	p := r.FormValue("pages")
+	if p == "" {
+		return 1, nil
+	}
+	pages, err := strconv.Atoi(p)
+	if err != nil || pages < 1 || pages > BulkSearchMaxPages {
+		return 0, &BadRequestError{"pages must be between 1 and " +
+			strconv.Itoa(BulkSearchMaxPages)}
+	}
+	return pages, nil
+}
+
+func bulkSearchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	query := r.FormValue("query")
+	cursor, err := searchRequestCursor(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	pages, err := bulkSearchPages(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	filter, err := newSearchFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), UpstreamTimeout)
+	defer cancel()
+	json, err := bulkSearchToJson(bulkSearch(ctx, query, cursor, pages, filter))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Write(json)
+}
+
+func bulkSearchToJson(resp *BulkSearchResponse) ([]byte, error) {
+	return json.Marshal(resp)
+}
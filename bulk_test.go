@@ -0,0 +1,52 @@
+package ck
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMergePages(t *testing.T) {
+	bohnen := allRecipes(detailDocument("testhtml/bohnen.html"))
+	sahne := allRecipes(detailDocument("testhtml/sahne.html"))
+	results := []*pageResult{
+		{recipes: bohnen, total: 5922},
+		{err: errors.New("upstream timeout")},
+		{recipes: append(bohnen[:5:5], sahne...), total: 5922},
+	}
+	resp := mergePages(results, 3, &searchFilter{})
+	if resp.Total != 5922 {
+		t.Errorf("Expected total to be 5922, got: %d", resp.Total)
+	}
+	if len(resp.Results) != len(bohnen)+len(sahne) {
+		t.Errorf("Expected %d results, got: %d", len(bohnen)+len(sahne),
+			len(resp.Results))
+	}
+	if resp.Results[len(bohnen)].Url != sahne[0].Url {
+		t.Errorf("Expected upstream order to be kept, got: %q",
+			resp.Results[len(bohnen)].Url)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Page != 4 {
+		t.Errorf("Expected an error for page 4, got: %+v", resp.Errors)
+	}
+}
+
+func TestBulkSearchPages(t *testing.T) {
+	for _, i := range []struct {
+		query string
+		pages int
+		err   bool
+	}{
+		{"", 1, false},
+		{"pages=10", 10, false},
+		{"pages=0", 0, true},
+		{"pages=21", 0, true},
+	} {
+		r := httptest.NewRequest("GET", "/searchpages?query=bohnen&"+i.query, nil)
+		pages, err := bulkSearchPages(r)
+		if (err != nil) != i.err || pages != i.pages {
+			t.Errorf("Expected %d pages for %q, got: %d, %v", i.pages, i.query,
+				pages, err)
+		}
+	}
+}
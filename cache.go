@@ -0,0 +1,138 @@
+package ck
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache stores upstream pages, or values parsed from them, by URL.
+type Cache interface {
+	// Get returns the value for key unless it is missing or expired.
+	Get(key string) (interface{}, bool)
+	// Set stores value under key for ttl.
+	Set(key string, value interface{}, ttl time.Duration)
+	Stats() CacheStats
+}
+
+// CacheStats counts the lookups of a Cache. Evictions include entries
+// dropped because they expired.
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+	Bytes     int    `json:"bytes"`
+}
+
+// Time to live of cached search result pages and recipe detail pages.
+var (
+	SearchTTL = 10 * time.Minute
+	DetailTTL = 24 * time.Hour
+)
+
+// PageCacheBytes is the default memory budget of PageCache. A chefkoch
+// page is about 350 KB, so it holds some 90 pages, which fits next to the
+// parsed documents on a small App Engine instance.
+const PageCacheBytes = 32 << 20
+
+// PageCache is used by the handlers for all upstream pages.
+var PageCache Cache = NewSizedLRUCache(500, PageCacheBytes)
+
+type lruEntry struct {
+	key     string
+	value   interface{}
+	size    int
+	expires time.Time
+}
+
+// LRUCache is an in-memory Cache holding at most capacity entries and,
+// unless maxBytes is 0, at most maxBytes bytes of []byte and string
+// values. When full it evicts the least recently used entries.
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	maxBytes int
+	bytes    int
+	entries  map[string]*list.Element
+	order    *list.List
+	stats    CacheStats
+	now      func() time.Time
+}
+
+func NewLRUCache(capacity int) *LRUCache {
+	return NewSizedLRUCache(capacity, 0)
+}
+
+// NewSizedLRUCache returns an LRUCache that is also limited to maxBytes.
+func NewSizedLRUCache(capacity, maxBytes int) *LRUCache {
+	return &LRUCache{capacity: capacity, maxBytes: maxBytes,
+		entries: make(map[string]*list.Element), order: list.New(), now: time.Now}
+}
+
+// valueSize returns the size of the pages kept in the cache. Other values
+// only count as entries.
+func valueSize(value interface{}) int {
+	switch v := value.(type) {
+	case []byte:
+		return len(v)
+	case string:
+		return len(v)
+	}
+	return 0
+}
+
+func (c *LRUCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	entry := el.Value.(*lruEntry)
+	if c.now().After(entry.expires) {
+		c.remove(el)
+		c.stats.Misses++
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	c.stats.Hits++
+	return entry.value, true
+}
+
+func (c *LRUCache) Set(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(ttl)
+	size := valueSize(value)
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*lruEntry)
+		c.bytes += size - entry.size
+		entry.value, entry.size, entry.expires = value, size, expires
+		c.order.MoveToFront(el)
+	} else {
+		c.entries[key] = c.order.PushFront(&lruEntry{key, value, size, expires})
+		c.bytes += size
+	}
+	for c.order.Len() > c.capacity || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRUCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	stats.Bytes = c.bytes
+	return stats
+}
+
+func (c *LRUCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	entry := el.Value.(*lruEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size
+	c.stats.Evictions++
+}
@@ -0,0 +1,68 @@
+package ck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCache(t *testing.T) {
+	now := time.Date(2019, 3, 2, 12, 0, 0, 0, time.UTC)
+	c := NewLRUCache(2)
+	c.now = func() time.Time { return now }
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Hour)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Expected a to be cached, got: %v, %v", v, ok)
+	}
+	// b is now the least recently used entry.
+	c.Set("c", 3, time.Hour)
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected a to be expired")
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Errorf("Expected c to be cached, got: %v, %v", v, ok)
+	}
+	want := CacheStats{Hits: 2, Misses: 2, Evictions: 2, Entries: 1}
+	if stats := c.Stats(); stats != want {
+		t.Errorf("Expected stats to be %+v, got: %+v", want, stats)
+	}
+}
+
+func TestLRUCacheUpdate(t *testing.T) {
+	c := NewLRUCache(2)
+	c.Set("a", 1, time.Hour)
+	c.Set("a", 2, time.Hour)
+	if v, _ := c.Get("a"); v != 2 {
+		t.Errorf("Expected a to be 2, got: %v", v)
+	}
+	if stats := c.Stats(); stats.Entries != 1 {
+		t.Errorf("Expected 1 entry, got: %d", stats.Entries)
+	}
+}
+
+func TestSizedLRUCache(t *testing.T) {
+	c := NewSizedLRUCache(10, 100)
+	c.Set("a", make([]byte, 40), time.Hour)
+	c.Set("b", make([]byte, 40), time.Hour)
+	c.Get("a")
+	// c does not fit next to a and b, so b, the least recently used, goes.
+	c.Set("c", make([]byte, 40), time.Hour)
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if stats := c.Stats(); stats.Entries != 2 || stats.Bytes != 80 {
+		t.Errorf("Expected 2 entries with 80 bytes, got: %+v", stats)
+	}
+	c.Set("a", make([]byte, 10), time.Hour)
+	if stats := c.Stats(); stats.Bytes != 50 {
+		t.Errorf("Expected the update to count 50 bytes, got: %+v", stats)
+	}
+	c.Set("d", make([]byte, 200), time.Hour)
+	if stats := c.Stats(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Errorf("Expected a page above the limit not to be kept, got: %+v", stats)
+	}
+}
@@ -1,63 +1,148 @@
 package ck
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 
-
 	"github.com/mswift42/goquery"
 )
 
 type Recipe struct {
-	Title      string `json:"title"`
-	Subtitle   string `json:"subtitle"`
-	Url        string `json:"url"`
-	Thumbnail  string `json:"thumbnail"`
-	Rating     string `json:"rating"`
-	Difficulty string `json:"difficulty"`
-	Preptime   string `json:"preptime"`
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	Subtitle        string `json:"subtitle"`
+	Url             string `json:"url"`
+	Thumbnail       string `json:"thumbnail"`
+	Rating          string `json:"rating"`
+	Difficulty      string `json:"difficulty"`
+	Preptime        string `json:"preptime"`
+	PreptimeMinutes int    `json:"preptimeminutes,omitempty"`
+	Votes           int    `json:"votes"`
 }
 
 type RecipeDetail struct {
-	Title       string              `json:"title"`
-	Rating      string              `json:"rating"`
-	Difficulty  string              `json:"difficulty"`
-	Preptime    string              `json:"preptime"`
-	Cookingtime string              `json:"cookingtime"`
-	Thumbnail   string              `json:"thumbnail"`
-	Ingredients []*RecipeIngredient `json:"ingredients"`
-	Method      string              `json:"method"`
+	ID                 string              `json:"id"`
+	Title              string              `json:"title"`
+	Rating             string              `json:"rating"`
+	Difficulty         string              `json:"difficulty"`
+	Preptime           string              `json:"preptime"`
+	Cookingtime        string              `json:"cookingtime"`
+	Restingtime        string              `json:"restingtime,omitempty"`
+	Calories           string              `json:"calories,omitempty"`
+	PreptimeMinutes    int                 `json:"preptimeminutes,omitempty"`
+	CookingtimeMinutes int                 `json:"cookingtimeminutes,omitempty"`
+	RestingtimeMinutes int                 `json:"restingtimeminutes,omitempty"`
+	TotaltimeMinutes   int                 `json:"totaltimeminutes,omitempty"`
+	Thumbnail          string              `json:"thumbnail"`
+	Image              string              `json:"image"`
+	Author             string              `json:"author"`
+	Servings           int                 `json:"servings"`
+	DatePublished      string              `json:"datepublished"`
+	Ingredients        []*RecipeIngredient `json:"ingredients"`
+	Method             string              `json:"method"`
+	Steps              []*RecipeStep       `json:"steps"`
+	Sources            map[string]string   `json:"sources"`
+	Warnings           []string            `json:"warnings,omitempty"`
 }
 
+// RecipeIngredient keeps the amount and ingredient text as shown on the
+// page next to their parsed form. Quantity is zero for ingredients
+// without one, such as "etwas Salz"; MaxQuantity is only set for ranges.
 type RecipeIngredient struct {
-	Amount     string `json:"amount"`
-	Ingredient string `json:"ingredient"`
+	Amount      string  `json:"amount"`
+	Ingredient  string  `json:"ingredient"`
+	Raw         string  `json:"raw"`
+	Quantity    float64 `json:"quantity,omitempty"`
+	MaxQuantity float64 `json:"maxquantity,omitempty"`
+	Unit        string  `json:"unit,omitempty"`
+	Name        string  `json:"name"`
+	Note        string  `json:"note,omitempty"`
 }
 
 type RecipeDetailDocument struct {
 	doc *goquery.Document
 }
 
+// newRecipeDetail fills a RecipeDetail from the page's JSON-LD block and
+// falls back to the CSS selectors for every field the structured data
+// does not carry. The origin of each field is recorded in Sources.
 func (rdd *RecipeDetailDocument) newRecipeDetail() *RecipeDetail {
-	title := rdd.title()
-	ingredients := rdd.ingredients()
-	method := rdd.method()
-	rating := rdd.rating()
+	ld := rdd.jsonld()
+	sources := make(map[string]string)
+	pick := func(field, structured string, scraped func() string) string {
+		if structured != "" {
+			sources[field] = SourceJSONLD
+			return structured
+		}
+		sources[field] = SourceSelector
+		return scraped()
+	}
 	prepinfo := rdd.prepinfo()
-	difficulty := rdd.difficulty(prepinfo)
-	preptime := rdd.preptime(prepinfo)
-	cookingtime := rdd.cookingtime(prepinfo)
-	thumbnail := rdd.thumbnail()
-	return &RecipeDetail{title, rating, difficulty,
-		preptime, cookingtime, thumbnail, ingredients, method}
+	rd := &RecipeDetail{}
+	rd.ID = pick("id", "", rdd.id)
+	rd.Title = pick("title", ld.title(), rdd.title)
+	rd.Rating = pick("rating", ld.rating(), rdd.rating)
+	fromPrepinfo := func(value string) func() string {
+		return func() string { return value }
+	}
+	rd.Difficulty = pick("difficulty", ldClean(ld.Difficulty),
+		fromPrepinfo(prepinfo.Difficulty))
+	rd.Preptime = pick("preptime", ldDisplayDuration(ld.PrepTime),
+		fromPrepinfo(prepinfo.Worktime))
+	rd.Cookingtime = pick("cookingtime", ldDisplayDuration(ld.CookTime),
+		fromPrepinfo(prepinfo.Cookingtime))
+	rd.Restingtime = pick("restingtime", "", fromPrepinfo(prepinfo.Restingtime))
+	rd.Calories = pick("calories", "", fromPrepinfo(prepinfo.Calories))
+	rd.PreptimeMinutes = pickMinutes(sources, "preptimeminutes",
+		ld.PrepTime, rd.Preptime)
+	rd.CookingtimeMinutes = pickMinutes(sources, "cookingtimeminutes",
+		ld.CookTime, rd.Cookingtime)
+	rd.RestingtimeMinutes = pickMinutes(sources, "restingtimeminutes",
+		"", rd.Restingtime)
+	rd.TotaltimeMinutes = pickMinutes(sources, "totaltimeminutes",
+		ld.TotalTime, "")
+	if rd.TotaltimeMinutes == 0 {
+		rd.TotaltimeMinutes = rd.PreptimeMinutes + rd.CookingtimeMinutes +
+			rd.RestingtimeMinutes
+	}
+	rd.Warnings = prepinfo.Warnings
+	rd.Thumbnail = pick("thumbnail", "", rdd.thumbnail)
+	rd.Image = pick("image", ld.image(), rdd.thumbnail)
+	rd.Author = pick("author", ld.author(), rdd.author)
+	rd.DatePublished = pick("datepublished", ldClean(ld.DatePublished),
+		rdd.datePublished)
+	rd.Servings, _ = strconv.Atoi(pick("servings", ld.servings(), rdd.servings))
+	rd.Method = pick("method", ld.method(), rdd.method)
+	rd.Steps = parseSteps(rd.Method)
+	rd.Ingredients = ld.ingredients()
+	sources["ingredients"] = SourceJSONLD
+	if len(rd.Ingredients) == 0 {
+		rd.Ingredients = rdd.ingredients()
+		sources["ingredients"] = SourceSelector
+	}
+	rd.Sources = sources
+	return rd
 }
 
 const CKPrefix = "https://www.chefkoch.de"
 
+// queryUrl returns the search result page for searchterm at the given
+// result offset, "0", "30" and so on. The term is escaped so that "/",
+// "?" or "#" in it stay part of the search.
 func queryUrl(searchterm string, page string) string {
-	return "https://www.chefkoch.de/rs/s" + page + "/" + searchterm + "/Rezepte.html#more2"
+	return "https://www.chefkoch.de/rs/s" + page + "/" + url.PathEscape(searchterm) + "/Rezepte.html#more2"
+}
+
+// pageOffset converts a page number starting at 1 into the result offset
+// taken by queryUrl.
+func pageOffset(page int) int {
+	return (page - 1) * SearchPageSize
 }
 
 type RecipesSelection struct {
@@ -93,6 +178,10 @@ func (rs *RecipesSelection) rating() string {
 
 }
 
+func (rdd *RecipeDetailDocument) id() string {
+	return recipeID(rdd.doc.Find(`link[rel="canonical"]`).AttrOr("href", ""))
+}
+
 func (rdd *RecipeDetailDocument) title() string {
 	return rdd.doc.Find(".page-title").Text()
 }
@@ -103,31 +192,34 @@ func (rdd *RecipeDetailDocument) rating() string {
 	return strings.Replace(rat, ",", ".", 1)
 }
 
-func (rdd *RecipeDetailDocument) difficulty(pi map[string]string) string {
-	return pi["Schwierigkeitsgrad"]
-}
-
-func (rdd *RecipeDetailDocument) preptime(pi map[string]string) string {
-	return pi["Arbeitszeit"]
+func (rdd *RecipeDetailDocument) author() string {
+	return strings.Trim(rdd.doc.Find(".recipe__author .author").First().Text(), " \n")
 }
 
-func (rdd *RecipeDetailDocument) cookingtime(pi map[string]string) string {
-	return pi["Kochzeit"]
+func (rdd *RecipeDetailDocument) datePublished() string {
+	var date string
+	rdd.doc.Find("td:contains('Freischaltung:')").Each(func(i int, s *goquery.Selection) {
+		date = strings.Trim(s.Next().Text(), " \n")
+	})
+	split := strings.Split(date, ".")
+	if len(split) != 3 {
+		return date
+	}
+	return split[2] + "-" + split[1] + "-" + split[0]
 }
 
-func (rdd *RecipeDetailDocument) prepinfo() map[string]string {
-	prep := rdd.doc.Find("#preparation-info").Text()
-	prep = strings.Replace(prep, "\n", "", -1)
-	prep = strings.Replace(prep, "Koch-/Backzeit", "Kochzeit", 1)
-	prep = strings.Replace(prep, "keine Angabe", "NA", 1)
-	sections := strings.Split(prep, "/")
-	result := make(map[string]string)
-	for _, i := range sections {
-		trimmed := strings.Trim(i, " \n\t")
-		split := strings.Split(trimmed, ":")
-		result[split[0]] = strings.Trim(split[1], " \n")
+// pickMinutes converts the ISO-8601 duration from JSON-LD, or the display
+// string if the former is missing, into minutes and records the source.
+func pickMinutes(sources map[string]string, field, iso, display string) int {
+	if d, ok := parseDuration(iso); ok {
+		sources[field] = SourceJSONLD
+		return minutes(d)
+	}
+	if d, ok := parseDuration(display); ok {
+		sources[field] = SourceSelector
+		return minutes(d)
 	}
-	return result
+	return 0
 }
 
 func (rdd *RecipeDetailDocument) thumbnail() string {
@@ -138,9 +230,9 @@ func (rdd *RecipeDetailDocument) ingredients() []*RecipeIngredient {
 	ingtable := rdd.doc.Find(".incredients>tbody>tr")
 	var ingredients []*RecipeIngredient
 	ingtable.Each(func(i int, s *goquery.Selection) {
-		amount := strings.Trim(s.Find(".amount").Text(), " \n")
-		ing := strings.Trim(s.Find("td:nth-child(2)").Text(), " \n")
-		ingredients = append(ingredients, &RecipeIngredient{amount, ing})
+		amount := s.Find(".amount").Text()
+		ing := s.Find("td:nth-child(2)").Text()
+		ingredients = append(ingredients, newIngredient(amount, ing))
 	})
 	return ingredients
 }
@@ -150,6 +242,12 @@ func (rdd *RecipeDetailDocument) method() string {
 	return strings.Trim(text, " \n")
 }
 
+func (rs *RecipesSelection) votes() int {
+	count := rs.sel.Find(".search-list-item-uservotes-count").Text()
+	votes, _ := strconv.Atoi(strings.Trim(count, " ()\n"))
+	return votes
+}
+
 func (rs *RecipesSelection) difficulty() string {
 	return rs.sel.Find(".search-list-item-difficulty").Text()
 }
@@ -160,9 +258,15 @@ func (rs *RecipesSelection) preptime() string {
 
 func NewRecipe(sel *goquery.Selection) *Recipe {
 	rs := &RecipesSelection{sel}
-	return &Recipe{rs.title(), rs.subtitle(),
-		rs.url(), rs.thumbnail(), rs.rating(), rs.difficulty(),
-		rs.preptime()}
+	r := &Recipe{Title: rs.title(), Subtitle: rs.subtitle(),
+		Url: rs.url(), Thumbnail: rs.thumbnail(), Rating: rs.rating(),
+		Difficulty: rs.difficulty(), Preptime: rs.preptime(),
+		Votes: rs.votes()}
+	r.ID = recipeID(r.Url)
+	if d, ok := parseDuration(r.Preptime); ok {
+		r.PreptimeMinutes = minutes(d)
+	}
+	return r
 }
 
 func allRecipes(doc *goquery.Document) []*Recipe {
@@ -182,50 +286,86 @@ func recipeDetailToJson(rd *RecipeDetail) ([]byte, error) {
 }
 
 func searchHandler(w http.ResponseWriter, r *http.Request) {
+	serveSearch(w, r, r.FormValue("query"))
+}
+
+// serveSearch answers a search request for query, reading the page,
+// cursor and filter parameters from r.
+func serveSearch(w http.ResponseWriter, r *http.Request, query string) {
 	w.Header().Add("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	query := r.FormValue("query")
-	page := r.FormValue("page")
-	url := queryUrl(query, page)
-	res, err := http.Get(url)
+	cursor, err := searchRequestCursor(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
-	defer res.Body.Close()
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+	filter, err := newSearchFilter(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
-	json, err := recipesToJson(allRecipes(doc))
+	ctx, cancel := context.WithTimeout(r.Context(), UpstreamTimeout)
+	defer cancel()
+	resp, err := searchRecipes(ctx, query, cursor, filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+	json, err := searchResponseToJson(resp)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 	w.Write(json)
 }
 
 func detailHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Add("Content-Type", "application/json; charset=utf-8")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	recurl := r.FormValue("recipeurl")
-	res, err := http.Get(recurl)
+	recurl, err := recipeURL(r.FormValue("recipeurl"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
-	defer res.Body.Close()
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+	serveRecipeDetail(w, r, recurl)
+}
+
+// serveRecipeDetail answers a detail request for the recipe at recurl,
+// scaled to the servings parameter of r, in the format requested by r.
+func serveRecipeDetail(w http.ResponseWriter, r *http.Request, recurl string) {
+	format, err := requestRecipeFormat(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+	servings := 0
+	if s := r.FormValue("servings"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			writeError(w, r, &BadRequestError{"servings must be a positive number"})
+			return
+		}
+		servings = n
 	}
-	rddoc := RecipeDetailDocument{doc}
-	rdd := rddoc.newRecipeDetail()
-	json, err := recipeDetailToJson(rdd)
+	ctx, cancel := context.WithTimeout(r.Context(), UpstreamTimeout)
+	defer cancel()
+	rdd, err := fetchRecipeDetail(ctx, recurl)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
-	w.Write(json)
-}
-func init() {
-	http.HandleFunc("/search", searchHandler)
-	http.HandleFunc("/recipedetail", detailHandler)
+	rdd.Scale(servings)
+	var buf bytes.Buffer
+	if err := format.write(&buf, rdd); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.contentType)
+	if format.extension != "" {
+		name := rdd.ID
+		if name == "" {
+			name = "recipe"
+		}
+		w.Header().Set("Content-Disposition",
+			`attachment; filename="`+name+format.extension+`"`)
+	}
+	w.Header().Add("Vary", "Accept")
+	w.Write(buf.Bytes())
 }
-
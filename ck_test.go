@@ -30,6 +30,16 @@ var queryurls = []struct {
 		"120",
 		"https://www.chefkoch.de/rs/s120/bohnen/Rezepte.html#more2",
 	},
+	{
+		"a/b?c#d",
+		"0",
+		"https://www.chefkoch.de/rs/s0/a%2Fb%3Fc%23d/Rezepte.html#more2",
+	},
+	{
+		"grüne bohnen",
+		"30",
+		"https://www.chefkoch.de/rs/s30/gr%C3%BCne%20bohnen/Rezepte.html#more2",
+	},
 }
 
 func TestQueryURL(t *testing.T) {
@@ -348,7 +358,7 @@ var grueneImSpeckmantel = struct {
 	"Grüne Bohnen im Speckmantel",
 	"https://static.chefkoch-cdn.de/ck.de/rezepte/56/56345/1124631-420x280-fix-gruene-bohnen-im-speckmantel.jpg",
 	[]*RecipeIngredient{
-		{"800\u00a0g", "Bohnen, frische"},
+		{Amount: "800\u00a0g", Ingredient: "Bohnen, frische"},
 	},
 	"Bohnen waschen und die Spitzen abschneiden.\nBohnenkraut, Knoblauch, zerdrückte Pfefferkörner und Salz mit Öl kurz anrösten. 2 Liter Wasser zugießen, 10 Min. kochen, durchsieben. Diese Brühe aufkochen und die Bohnen in 3 Portionen nacheinander sprudelnd garen. Schnell in kaltem Wasser abkühlen, in einem Tuch abtrocknen.\n\nBohnen in Bacon einwickeln. Butter in einer feuerfesten Form erhitzen, die Bohnen reingeben (mit der Specknaht nach unten) und zugedeckt im Ofen bei 180 °C - 200 °C erhitzen (ca. 5 Minuten), dabei einmal wenden.",
 	"4.49",
@@ -370,14 +380,14 @@ var schupfnudel = struct {
 	"Schupfnudel - Bohnen - Pfanne",
 	"https://static.chefkoch-cdn.de/ck.de/rezepte/117/117138/1156413-420x280-fix-schupfnudel-bohnen-pfanne.jpg",
 	[]*RecipeIngredient{
-		{"500\u00a0g", "Schupfnudeln (Kühlregal)"},
-		{"200\u00a0g", "Schinken, gekochter"},
-		{"250\u00a0g", "Bohnen (Prinzessbohnen, TK)"},
-		{"1/8\u00a0Liter", "Fleischbrühe"},
-		{"1\u00a0Becher", "Crème fraîche"},
-		{"4\u00a0Scheibe/n", "Käse (Toast-Käse, z.B. Scheibletten)"},
-		{"\u00a0n. B.", "Salz und Pfeffer"},
-		{"\u00a0", "Olivenöl"},
+		{Amount: "500\u00a0g", Ingredient: "Schupfnudeln (Kühlregal)"},
+		{Amount: "200\u00a0g", Ingredient: "Schinken, gekochter"},
+		{Amount: "250\u00a0g", Ingredient: "Bohnen (Prinzessbohnen, TK)"},
+		{Amount: "1/8\u00a0Liter", Ingredient: "Fleischbrühe"},
+		{Amount: "1\u00a0Becher", Ingredient: "Crème fraîche"},
+		{Amount: "4\u00a0Scheibe/n", Ingredient: "Käse (Toast-Käse, z.B. Scheibletten)"},
+		{Amount: "\u00a0n. B.", Ingredient: "Salz und Pfeffer"},
+		{Amount: "\u00a0", Ingredient: "Olivenöl"},
 	},
 	"Die Prinzessböhnchen für ca. 5 Min. in kochendem Wasser garen. \n\nDen Kochschinken würfeln und mit etwas Olivenöl in der Pfanne anbraten. Die Schupfnudeln hinzugeben und 5-8 Min. zusammen mit dem Schinken braten, bis die Schupfnudeln eine goldgelbe Farbe annehmen. Die Prinzessbohnen hinzu geben. Nun 1/8 l Fleischbrühe zugießen und mit Crème fraiche nach Belieben andicken. Nach Geschmack würzen. Als Abschluss die Käsescheiben oben auflegen, bis diese verlaufen. Sofort servieren.",
 	"4.37",
@@ -399,7 +409,7 @@ var speckbohnen = struct {
 	"Grüne Bohnen mit Speck",
 	"https://static.chefkoch-cdn.de/ck.de/rezepte/240/240661/1135575-420x280-fix-gruene-bohnen-mit-speck.jpg",
 	[]*RecipeIngredient{
-		{"500\u00a0g", "Bohnen, grüne, frisch oder TK"},
+		{Amount: "500\u00a0g", Ingredient: "Bohnen, grüne, frisch oder TK"},
 	},
 	"Grüne Bohnen putzen, ca. 5 Min. in Salzwasser ankochen (bei TK nach Anleitung kochen). Speck würfeln und im Butter-Öl Gemisch kross anbraten. Bohnen, Speck und Bohnenkraut zusammen in einen Topf geben, pfeffern und 10-20 Min. bei kleiner Hitze ziehen lassen, gelegentlich umrühren. Wem es zu kräftig (salzig) ist, einfach weniger Speck nehmen.",
 	"4.67",
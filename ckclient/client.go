@@ -0,0 +1,281 @@
+// Package ckclient is a client for the ck API. It does not depend on
+// package ck; the response types are mirrored in this package.
+package ckclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client calls the ck API at BaseURL, e.g. "https://ck.example.com",
+// using HTTPClient.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// New returns a Client for the API at baseURL using http.DefaultClient.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: baseURL, HTTPClient: http.DefaultClient}
+}
+
+// SearchOptions are the optional parameters of a search. Zero values are
+// not sent. Cursor takes precedence over Page.
+type SearchOptions struct {
+	Page       int
+	Cursor     string
+	Difficulty []string
+	MaxTime    int
+	MinRating  float64
+	MinVotes   int
+}
+
+func (o *SearchOptions) values(query string) url.Values {
+	v := url.Values{"q": {query}}
+	if o == nil {
+		return v
+	}
+	if o.Cursor != "" {
+		v.Set("cursor", o.Cursor)
+	} else if o.Page > 0 {
+		v.Set("pagenum", strconv.Itoa(o.Page))
+	}
+	if len(o.Difficulty) > 0 {
+		v.Set("difficulty", strings.Join(o.Difficulty, ","))
+	}
+	if o.MaxTime > 0 {
+		v.Set("maxtime", strconv.Itoa(o.MaxTime))
+	}
+	if o.MinRating > 0 {
+		v.Set("minrating", strconv.FormatFloat(o.MinRating, 'f', -1, 64))
+	}
+	if o.MinVotes > 0 {
+		v.Set("minvotes", strconv.Itoa(o.MinVotes))
+	}
+	return v
+}
+
+// Search returns one page of results for query.
+func (c *Client) Search(ctx context.Context, query string, opts *SearchOptions) (*SearchResponse, error) {
+	var resp SearchResponse
+	if err := c.get(ctx, "/v1/search", opts.values(query), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Detail returns the recipe with the given chefkoch ID or URL.
+func (c *Client) Detail(ctx context.Context, idOrURL string) (*RecipeDetail, error) {
+	return c.DetailServings(ctx, idOrURL, 0)
+}
+
+// DetailServings returns the recipe with the given chefkoch ID or URL
+// with its ingredients scaled to servings. A servings of 0 keeps the
+// recipe's own portion count.
+func (c *Client) DetailServings(ctx context.Context, idOrURL string, servings int) (*RecipeDetail, error) {
+	path, v := "/v1/recipes/"+url.PathEscape(idOrURL), url.Values{}
+	if !isID(idOrURL) {
+		path = "/recipedetail"
+		v.Set("recipeurl", idOrURL)
+	}
+	if servings > 0 {
+		v.Set("servings", strconv.Itoa(servings))
+	}
+	var rd RecipeDetail
+	if err := c.get(ctx, path, v, &rd); err != nil {
+		return nil, err
+	}
+	return &rd, nil
+}
+
+func isID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// get requests path with the query v and decodes the JSON response into
+// result, or returns an *Error for a failed request.
+func (c *Client) get(ctx context.Context, path string, v url.Values, result interface{}) error {
+	u := strings.TrimSuffix(c.BaseURL, "/") + path
+	if len(v) > 0 {
+		u += "?" + v.Encode()
+	}
+	return c.do(ctx, "GET", u, nil, result)
+}
+
+// do sends a request with the JSON encoded body, unless body is nil, and
+// decodes the JSON response into result.
+func (c *Client) do(ctx context.Context, method, u string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode/100 != 2 {
+		return newError(res, b)
+	}
+	return json.Unmarshal(b, result)
+}
+
+// ShoppingList returns the merged ingredients of recipes, each given by
+// id or URL and the servings to scale it to.
+func (c *Client) ShoppingList(ctx context.Context, recipes []*ShoppingListRecipe) (*ShoppingList, error) {
+	var list ShoppingList
+	u := strings.TrimSuffix(c.BaseURL, "/") + "/shoppinglist"
+	req := &ShoppingListRequest{Recipes: recipes}
+	if err := c.do(ctx, "POST", u, req, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// CreatePlan stores plan as a new meal plan and returns it with its id.
+func (c *Client) CreatePlan(ctx context.Context, plan *MealPlan) (*MealPlan, error) {
+	return c.plan(ctx, "POST", "/v1/plans", plan)
+}
+
+// Plan returns the meal plan with id.
+func (c *Client) Plan(ctx context.Context, id string) (*MealPlan, error) {
+	return c.plan(ctx, "GET", "/v1/plans/"+url.PathEscape(id), nil)
+}
+
+// UpdatePlan replaces the name, start, days and slots of the meal plan
+// with plan.ID.
+func (c *Client) UpdatePlan(ctx context.Context, plan *MealPlan) (*MealPlan, error) {
+	return c.plan(ctx, "PUT", "/v1/plans/"+url.PathEscape(plan.ID), plan)
+}
+
+// PlanShoppingList returns the merged ingredients of all meals of the
+// plan with id.
+func (c *Client) PlanShoppingList(ctx context.Context, id string) (*ShoppingList, error) {
+	var list ShoppingList
+	if err := c.get(ctx, "/v1/plans/"+url.PathEscape(id)+"/shoppinglist", nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (c *Client) plan(ctx context.Context, method, path string, body *MealPlan) (*MealPlan, error) {
+	var plan MealPlan
+	u := strings.TrimSuffix(c.BaseURL, "/") + path
+	// A nil *MealPlan would be sent as null.
+	var b interface{}
+	if body != nil {
+		b = body
+	}
+	if err := c.do(ctx, method, u, b, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// SearchIterator steps through the results of a search page by page,
+// following the server's cursors:
+//
+//	it := client.SearchAll(ctx, "bohnen", nil)
+//	for it.Next() {
+//		fmt.Println(it.Recipe().Title)
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type SearchIterator struct {
+	client  *Client
+	ctx     context.Context
+	query   string
+	opts    SearchOptions
+	page    *SearchResponse
+	index   int
+	done    bool
+	err     error
+	current *Recipe
+}
+
+// SearchAll returns an iterator over all results for query, starting at
+// the page or cursor given in opts.
+func (c *Client) SearchAll(ctx context.Context, query string, opts *SearchOptions) *SearchIterator {
+	it := &SearchIterator{client: c, ctx: ctx, query: query}
+	if opts != nil {
+		it.opts = *opts
+	}
+	return it
+}
+
+// Next advances to the next recipe, fetching the next page when needed.
+// It returns false when the results are exhausted or a request failed.
+func (it *SearchIterator) Next() bool {
+	for it.page == nil || it.index >= len(it.page.Results) {
+		if it.done || it.err != nil {
+			return false
+		}
+		if it.page != nil {
+			if it.page.Next == "" {
+				it.done = true
+				return false
+			}
+			it.opts.Cursor = it.page.Next
+		}
+		it.page, it.err = it.client.Search(it.ctx, it.query, &it.opts)
+		it.index = 0
+		if it.err != nil {
+			return false
+		}
+	}
+	it.current = it.page.Results[it.index]
+	it.index++
+	return true
+}
+
+// Recipe returns the current recipe.
+func (it *SearchIterator) Recipe() *Recipe {
+	return it.current
+}
+
+// Page returns the search response the current recipe belongs to.
+func (it *SearchIterator) Page() *SearchResponse {
+	return it.page
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *SearchIterator) Err() error {
+	return it.err
+}
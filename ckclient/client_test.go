@@ -0,0 +1,255 @@
+package ckclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mswift42/ck"
+)
+
+type testFetcher map[string]string
+
+func (f testFetcher) Fetch(ctx context.Context, url string) (*ck.Page, error) {
+	file, ok := f[url]
+	if !ok {
+		return &ck.Page{URL: url, StatusCode: 404}, nil
+	}
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return &ck.Page{URL: url, StatusCode: 200, Body: body}, nil
+}
+
+// testServer serves the ck API for the recipes in testhtml.
+func testServer() (*httptest.Server, func()) {
+	oldFetcher, oldCache := ck.DefaultFetcher, ck.PageCache
+	ck.DefaultFetcher = testFetcher{
+		"https://www.chefkoch.de/rezepte/1171381223217983/":                               "../testhtml/schupfnudel.html",
+		"https://www.chefkoch.de/rezepte/1171381223217983/Schupfnudel-Bohnen-Pfanne.html": "../testhtml/schupfnudel.html",
+		"https://www.chefkoch.de/rs/s0/bohnen/Rezepte.html#more2":                         "../testhtml/bohnen.html",
+	}
+	ck.PageCache = ck.NewLRUCache(10)
+	ts := httptest.NewServer(ck.NewServeMux("*"))
+	return ts, func() {
+		ts.Close()
+		ck.DefaultFetcher, ck.PageCache = oldFetcher, oldCache
+	}
+}
+
+func TestDetail(t *testing.T) {
+	ts, done := testServer()
+	defer done()
+	c := New(ts.URL)
+	ctx := context.Background()
+	for _, i := range []string{"1171381223217983",
+		"https://www.chefkoch.de/rezepte/1171381223217983/Schupfnudel-Bohnen-Pfanne.html"} {
+		rd, err := c.Detail(ctx, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rd.Title != "Schupfnudel - Bohnen - Pfanne" || rd.ID != "1171381223217983" {
+			t.Errorf("Expected the Schupfnudel - Bohnen - Pfanne for %q, got: %q (%s)",
+				i, rd.Title, rd.ID)
+		}
+		if len(rd.Ingredients) == 0 || len(rd.Steps) == 0 {
+			t.Errorf("Expected ingredients and steps, got: %d and %d",
+				len(rd.Ingredients), len(rd.Steps))
+		}
+	}
+	rd, err := c.DetailServings(ctx, "1171381223217983", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rd.Servings != 6 {
+		t.Errorf("Expected 6 servings, got: %d", rd.Servings)
+	}
+}
+
+func TestShoppingList(t *testing.T) {
+	ts, done := testServer()
+	defer done()
+	c := New(ts.URL)
+	list, err := c.ShoppingList(context.Background(), []*ShoppingListRecipe{
+		{ID: "1171381223217983", Servings: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Recipes) != 1 || list.Recipes[0].Servings != 4 || len(list.Aisles) == 0 {
+		t.Errorf("Expected a list for 4 servings, got: %+v", list)
+	}
+	_, err = c.ShoppingList(context.Background(), nil)
+	if e, ok := err.(*Error); !ok || e.Code != CodeBadRequest {
+		t.Errorf("Expected a bad request error, got: %v", err)
+	}
+}
+
+func TestPlans(t *testing.T) {
+	ts, done := testServer()
+	defer done()
+	c := New(ts.URL)
+	ctx := context.Background()
+	plan, err := c.CreatePlan(ctx, &MealPlan{Start: "2026-10-19", Slots: []*MealSlot{
+		{Day: 1, Meal: MealDinner, RecipeID: "1171381223217983"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Slots[0].Servings = 4
+	if _, err := c.UpdatePlan(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Plan(ctx, plan.ID)
+	if err != nil || got.Days != 7 || got.Slots[0].Servings != 4 {
+		t.Errorf("Expected the updated plan, got: %+v %v", got, err)
+	}
+	list, err := c.PlanShoppingList(ctx, plan.ID)
+	if err != nil || len(list.Recipes) != 1 || list.Recipes[0].Servings != 4 {
+		t.Errorf("Expected the shopping list for 4 servings, got: %+v %v", list, err)
+	}
+	_, err = c.Plan(ctx, "0000000000000000")
+	if e, ok := err.(*Error); !ok || !e.NotFound() {
+		t.Errorf("Expected a not found error, got: %v", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ts, done := testServer()
+	defer done()
+	resp, err := New(ts.URL).Search(context.Background(), "bohnen", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 5922 || len(resp.Results) != 30 || resp.Next != "30" {
+		t.Errorf("Expected 30 of 5922 results with next cursor 30, got: %d of %d, %q",
+			len(resp.Results), resp.Total, resp.Next)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ts, done := testServer()
+	defer done()
+	c := New(ts.URL)
+	ctx := context.Background()
+	_, err := c.Detail(ctx, "http://169.254.169.254/")
+	if e, ok := err.(*Error); !ok || e.StatusCode != 400 || e.Code != CodeBadRequest ||
+		e.RequestID == "" {
+		t.Errorf("Expected a bad request error, got: %v", err)
+	}
+	_, err = c.Detail(ctx, "1")
+	if e, ok := err.(*Error); !ok || !e.NotFound() || e.Temporary() {
+		t.Errorf("Expected a not found error, got: %v", err)
+	}
+	_, err = c.Search(ctx, "bohnen", &SearchOptions{Difficulty: []string{"schwer"}})
+	if e, ok := err.(*Error); !ok || e.Code != CodeBadRequest {
+		t.Errorf("Expected a bad request error, got: %v", err)
+	}
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		http.Error(w, "upstream overloaded", http.StatusServiceUnavailable)
+	}))
+	defer proxy.Close()
+	_, err = New(proxy.URL).Detail(ctx, "1")
+	e, ok := err.(*Error)
+	if !ok || e.Code != "" || e.Message != "upstream overloaded" || !e.Temporary() ||
+		e.RetryAfter.Seconds() != 3 {
+		t.Errorf("Expected a temporary error from the proxy, got: %#v", err)
+	}
+}
+
+func TestErrorMessageTruncated(t *testing.T) {
+	body := "x" + strings.Repeat("ä", 150)
+	e := newError(&http.Response{StatusCode: 502, Header: http.Header{}}, []byte(body))
+	if !utf8.ValidString(e.Message) || e.Message != body[:199] {
+		t.Errorf("Expected the message to be cut before a whole rune, got: %q", e.Message)
+	}
+}
+
+func TestSearchIterator(t *testing.T) {
+	var cursors []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.FormValue("cursor")
+		cursors = append(cursors, cursor)
+		if r.FormValue("q") != "bohnen" || r.FormValue("maxtime") != "20" {
+			t.Errorf("Expected q=bohnen&maxtime=20, got: %s", r.URL.RawQuery)
+		}
+		resp := &SearchResponse{Query: "bohnen", Total: 7}
+		switch cursor {
+		case "":
+			resp.Next = "30.2"
+			resp.Results = []*Recipe{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+		case "30.2":
+			resp.Next = "60"
+		case "60":
+			resp.Results = []*Recipe{{ID: "4"}, {ID: "5"}, {ID: "6"}, {ID: "7"}}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+	it := New(ts.URL).SearchAll(context.Background(), "bohnen", &SearchOptions{MaxTime: 20})
+	var ids string
+	for it.Next() {
+		ids += it.Recipe().ID
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if ids != "1234567" || fmt.Sprint(cursors) != "[ 30.2 60]" {
+		t.Errorf("Expected recipes 1234567 from 3 pages, got: %s from %q", ids, cursors)
+	}
+	if it.Next() {
+		t.Error("Expected the iterator to stay exhausted")
+	}
+
+	it = New("http://127.0.0.1:0").SearchAll(context.Background(), "bohnen", nil)
+	if it.Next() || it.Err() == nil {
+		t.Error("Expected the iterator to stop with an error")
+	}
+}
+
+// jsonFields returns the JSON names of the fields of the struct t and of
+// the structs it refers to, qualified by the enclosing field names.
+func jsonFields(t reflect.Type, prefix string, fields map[string]bool) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t.PkgPath() == "time" {
+		return
+	}
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		name := prefix + f.Tag.Get("json")
+		fields[name+" "+f.Type.Kind().String()] = true
+		jsonFields(f.Type, name+".", fields)
+	}
+}
+
+func TestWireTypes(t *testing.T) {
+	tests := []struct {
+		server, client interface{}
+	}{
+		{ck.SearchResponse{}, SearchResponse{}},
+		{ck.RecipeDetail{}, RecipeDetail{}},
+		{ck.ShoppingListRequest{}, ShoppingListRequest{}},
+		{ck.ShoppingList{}, ShoppingList{}},
+		{ck.MealPlan{}, MealPlan{}},
+		{ck.ErrorResponse{}, errorResponse{}},
+	}
+	for _, i := range tests {
+		server, client := make(map[string]bool), make(map[string]bool)
+		jsonFields(reflect.TypeOf(i.server), "", server)
+		jsonFields(reflect.TypeOf(i.client), "", client)
+		if !reflect.DeepEqual(server, client) {
+			t.Errorf("Expected %T to match %T, got: %v and %v",
+				i.client, i.server, client, server)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ckclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Error codes sent by the server.
+const (
+	CodeBadRequest          = "bad_request"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodePlanNotFound        = "plan_not_found"
+	CodeUpstreamNotFound    = "upstream_not_found"
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	CodeUpstreamTimeout     = "upstream_timeout"
+	CodeParseFailure        = "parse_failure"
+	CodeNotCached           = "not_cached"
+	CodeDisallowed          = "disallowed"
+	CodeRateLimited         = "rate_limited"
+	CodeInternal            = "internal"
+)
+
+// Error is a request the server answered with an error response. Code is
+// empty if the response did not carry a ck error body, for instance when
+// it came from a proxy.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+	// RetryAfter is set for rate limited requests.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("ck: %d %s", e.StatusCode, e.Message)
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.RequestID != "" {
+		msg += " [request " + e.RequestID + "]"
+	}
+	return msg
+}
+
+// NotFound reports whether the requested recipe or meal plan does not
+// exist.
+func (e *Error) NotFound() bool {
+	return e.Code == CodeUpstreamNotFound || e.Code == CodeNotCached ||
+		e.Code == CodePlanNotFound
+}
+
+// Temporary reports whether the request may succeed when retried later.
+func (e *Error) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return e.Code != CodeParseFailure
+	}
+	return false
+}
+
+func newError(res *http.Response, body []byte) *Error {
+	e := &Error{StatusCode: res.StatusCode}
+	var resp errorResponse
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Code != "" {
+		e.Code, e.Message, e.RequestID = resp.Code, resp.Message, resp.RequestID
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+		if len(e.Message) > 200 {
+			n := 200
+			for n > 0 && !utf8.RuneStart(e.Message[n]) {
+				n--
+			}
+			e.Message = e.Message[:n]
+		}
+		if e.Message == "" {
+			e.Message = http.StatusText(res.StatusCode)
+		}
+		e.RequestID = res.Header.Get("X-Request-Id")
+	}
+	if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(s) * time.Second
+	}
+	return e
+}
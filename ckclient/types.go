@@ -0,0 +1,172 @@
+package ckclient
+
+import "time"
+
+// The types below mirror the JSON responses of the ck server. They are
+// kept here so that API consumers do not link the scraper and server.
+
+// SearchResponse is one page of search results. Next and Prev are cursors
+// to pass back as SearchOptions.Cursor; they are empty when there is no
+// such page.
+type SearchResponse struct {
+	Version  int       `json:"version"`
+	Query    string    `json:"query"`
+	Total    int       `json:"total"`
+	Page     int       `json:"page"`
+	PageSize int       `json:"pagesize"`
+	Next     string    `json:"next,omitempty"`
+	Prev     string    `json:"prev,omitempty"`
+	Results  []*Recipe `json:"results"`
+}
+
+// Recipe is a search result.
+type Recipe struct {
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	Subtitle        string `json:"subtitle"`
+	Url             string `json:"url"`
+	Thumbnail       string `json:"thumbnail"`
+	Rating          string `json:"rating"`
+	Difficulty      string `json:"difficulty"`
+	Preptime        string `json:"preptime"`
+	PreptimeMinutes int    `json:"preptimeminutes,omitempty"`
+	Votes           int    `json:"votes"`
+}
+
+// RecipeDetail is a full recipe.
+type RecipeDetail struct {
+	ID                 string              `json:"id"`
+	Title              string              `json:"title"`
+	Rating             string              `json:"rating"`
+	Difficulty         string              `json:"difficulty"`
+	Preptime           string              `json:"preptime"`
+	Cookingtime        string              `json:"cookingtime"`
+	Restingtime        string              `json:"restingtime,omitempty"`
+	Calories           string              `json:"calories,omitempty"`
+	PreptimeMinutes    int                 `json:"preptimeminutes,omitempty"`
+	CookingtimeMinutes int                 `json:"cookingtimeminutes,omitempty"`
+	RestingtimeMinutes int                 `json:"restingtimeminutes,omitempty"`
+	TotaltimeMinutes   int                 `json:"totaltimeminutes,omitempty"`
+	Thumbnail          string              `json:"thumbnail"`
+	Image              string              `json:"image"`
+	Author             string              `json:"author"`
+	Servings           int                 `json:"servings"`
+	DatePublished      string              `json:"datepublished"`
+	Ingredients        []*RecipeIngredient `json:"ingredients"`
+	Method             string              `json:"method"`
+	Steps              []*RecipeStep       `json:"steps"`
+	Sources            map[string]string   `json:"sources"`
+	Warnings           []string            `json:"warnings,omitempty"`
+}
+
+// RecipeIngredient is an ingredient as shown on the page next to its
+// parsed form. MaxQuantity is only set for ranges.
+type RecipeIngredient struct {
+	Amount      string  `json:"amount"`
+	Ingredient  string  `json:"ingredient"`
+	Raw         string  `json:"raw"`
+	Quantity    float64 `json:"quantity,omitempty"`
+	MaxQuantity float64 `json:"maxquantity,omitempty"`
+	Unit        string  `json:"unit,omitempty"`
+	Name        string  `json:"name"`
+	Note        string  `json:"note,omitempty"`
+}
+
+// RecipeStep is one paragraph of the method together with the timers
+// and oven temperatures it mentions.
+type RecipeStep struct {
+	Text         string             `json:"text"`
+	Timers       []*StepTimer       `json:"timers,omitempty"`
+	Temperatures []*StepTemperature `json:"temperatures,omitempty"`
+}
+
+// StepTimer is a duration mentioned in a step. MaxMinutes is only set
+// for ranges.
+type StepTimer struct {
+	Text       string `json:"text"`
+	Minutes    int    `json:"minutes"`
+	MaxMinutes int    `json:"maxminutes,omitempty"`
+}
+
+// StepTemperature is an oven setting mentioned in a step. Celsius is zero
+// when only the mode is given.
+type StepTemperature struct {
+	Text       string `json:"text"`
+	Celsius    int    `json:"celsius,omitempty"`
+	MaxCelsius int    `json:"maxcelsius,omitempty"`
+	Mode       string `json:"mode,omitempty"`
+}
+
+// ShoppingListRecipe is a recipe of a shopping list. ID is a recipe id or
+// chefkoch URL; Servings zero keeps the servings of the recipe.
+type ShoppingListRecipe struct {
+	ID       string `json:"id"`
+	Title    string `json:"title,omitempty"`
+	Servings int    `json:"servings,omitempty"`
+}
+
+// ShoppingListRequest is the body of POST /shoppinglist.
+type ShoppingListRequest struct {
+	Recipes []*ShoppingListRecipe `json:"recipes"`
+}
+
+// ShoppingList holds the merged ingredients of several recipes, grouped
+// by supermarket aisle.
+type ShoppingList struct {
+	Recipes []*ShoppingListRecipe `json:"recipes"`
+	Aisles  []*ShoppingAisle      `json:"aisles"`
+}
+
+// ShoppingAisle is a supermarket section and the items bought there.
+type ShoppingAisle struct {
+	Name  string          `json:"name"`
+	Items []*ShoppingItem `json:"items"`
+}
+
+// ShoppingItem is an ingredient summed over the recipes listed by id in
+// Recipes.
+type ShoppingItem struct {
+	Name        string   `json:"name"`
+	Amount      string   `json:"amount"`
+	Quantity    float64  `json:"quantity,omitempty"`
+	MaxQuantity float64  `json:"maxquantity,omitempty"`
+	Unit        string   `json:"unit,omitempty"`
+	Notes       []string `json:"notes,omitempty"`
+	Recipes     []string `json:"recipes"`
+}
+
+// Meals of a MealSlot.
+const (
+	MealBreakfast = "breakfast"
+	MealLunch     = "lunch"
+	MealDinner    = "dinner"
+)
+
+// MealPlan assigns recipes to the meals of Days consecutive days,
+// starting at the date Start, e.g. "2026-10-19".
+type MealPlan struct {
+	ID      string      `json:"id"`
+	Name    string      `json:"name,omitempty"`
+	Start   string      `json:"start"`
+	Days    int         `json:"days"`
+	Slots   []*MealSlot `json:"slots"`
+	Created time.Time   `json:"created"`
+	Updated time.Time   `json:"updated"`
+}
+
+// MealSlot is the recipe planned for a meal. Day counts from 1; Servings
+// zero keeps the servings of the recipe.
+type MealSlot struct {
+	Day      int    `json:"day"`
+	Meal     string `json:"meal"`
+	RecipeID string `json:"recipeid"`
+	Title    string `json:"title,omitempty"`
+	Servings int    `json:"servings,omitempty"`
+}
+
+// errorResponse is the body of a failed request.
+type errorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestid"`
+}
@@ -0,0 +1,283 @@
+// Command ck searches chefkoch.de and shows recipes in the terminal
+// without running the web service.
+//
+// Usage:
+//
+//	ck search <term> [--page N] [--min-rating R] [--json] [--input file.html]
+//	ck show <id|url> [--servings N] [--input file.html]
+//	ck export <id|url>... [--format md|json|txt|html|cooklang|jsonld|mealmaster|paprika] [--servings N] [--input file.html]
+//
+// With --input, a saved chefkoch page is parsed instead of fetching one.
+// show and export also read Cooklang files ending in .cook. The
+// mealmaster and paprika formats export several recipes into one file.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mswift42/ck"
+)
+
+const usage = `usage:
+  ck search <term> [--page N] [--min-rating R] [--json] [--input file.html]
+  ck show <id|url> [--servings N] [--input file.html]
+  ck export <id|url>... [--format md|json|txt|html|cooklang|jsonld|mealmaster|paprika] [--servings N] [--input file.html]
+`
+
+// usageError is returned for invalid command lines.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func main() {
+	if err := ck.ConfigureFromEnv(); err != nil {
+		fmt.Fprintln(os.Stderr, "ck:", err)
+		os.Exit(1)
+	}
+	err := run(os.Args[1:], os.Stdout)
+	if err == nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "ck:", err)
+	if _, ok := err.(usageError); ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	os.Exit(1)
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return usageError("missing command")
+	}
+	switch args[0] {
+	case "search":
+		return searchCommand(args[1:], stdout)
+	case "show":
+		return showCommand(args[1:], stdout)
+	case "export":
+		return exportCommand(args[1:], stdout)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
+	return usageError("unknown command " + args[0])
+}
+
+// parseArgs parses args with fs, allowing flags after positional
+// arguments as in "ck search bohnen --page 2", and returns the
+// positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(ioutil.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usageError(err.Error())
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func searchCommand(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	page := fs.Int("page", 1, "result page")
+	minRating := fs.Float64("min-rating", 0, "minimum rating")
+	asJSON := fs.Bool("json", false, "print JSON")
+	input := fs.String("input", "", "saved search result page")
+	terms, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *page < 1 {
+		return usageError("--page must be a positive number")
+	}
+	opts := &ck.SearchOptions{Page: *page, MinRating: *minRating}
+	var recipes []*ck.Recipe
+	footer := ""
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		all, err := ck.ParseRecipes(f)
+		if err != nil {
+			return err
+		}
+		for _, i := range all {
+			if opts.Match(i) {
+				recipes = append(recipes, i)
+			}
+		}
+	} else {
+		if len(terms) == 0 {
+			return usageError("missing search term")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), ck.UpstreamTimeout)
+		defer cancel()
+		resp, err := ck.Search(ctx, strings.Join(terms, " "), opts)
+		if err != nil {
+			return err
+		}
+		recipes = resp.Results
+		footer = fmt.Sprintf("Page %d, %d recipes in total", resp.Page, resp.Total)
+	}
+	if *asJSON {
+		if recipes == nil {
+			recipes = []*ck.Recipe{}
+		}
+		return writeJSON(stdout, recipes)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tRATING\tVOTES\tDIFFICULTY\tTIME")
+	for _, i := range recipes {
+		time := "-"
+		if i.PreptimeMinutes > 0 {
+			time = fmt.Sprintf("%d min", i.PreptimeMinutes)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", i.ID, truncate(i.Title, 48),
+			i.Rating, i.Votes, i.Difficulty, time)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if footer != "" {
+		fmt.Fprintln(stdout, footer)
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	r := []rune(strings.TrimSpace(s))
+	if len(r) <= n {
+		return string(r)
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// recipe returns the recipe named by the single positional argument, or
+// parsed from input, scaled to servings.
+func recipe(args []string, input string, servings int) (*ck.RecipeDetail, error) {
+	if servings < 0 {
+		return nil, usageError("--servings must be a positive number")
+	}
+	if input != "" {
+		if len(args) > 0 {
+			return nil, usageError("either give a recipe or --input")
+		}
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		parse := ck.ParseRecipeDetail
+		if strings.HasSuffix(input, ".cook") {
+			parse = ck.ParseCooklang
+		}
+		rd, err := parse(f)
+		if err != nil {
+			return nil, err
+		}
+		rd.Scale(servings)
+		return rd, nil
+	}
+	if len(args) != 1 {
+		return nil, usageError("expected one recipe id or url")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ck.UpstreamTimeout)
+	defer cancel()
+	return ck.Detail(ctx, args[0], servings)
+}
+
+func showCommand(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("show", flag.ContinueOnError)
+	servings := fs.Int("servings", 0, "scale to servings")
+	input := fs.String("input", "", "saved recipe page")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	rd, err := recipe(args, *input, *servings)
+	if err != nil {
+		return err
+	}
+	return ck.WriteText(stdout, rd)
+}
+
+func exportCommand(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "md",
+		"md, json, txt, html, cooklang, jsonld, mealmaster or paprika")
+	servings := fs.Int("servings", 0, "scale to servings")
+	input := fs.String("input", "", "saved recipe page")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	var write func(io.Writer, *ck.RecipeDetail) error
+	var bundle func(io.Writer, []*ck.RecipeDetail) error
+	switch *format {
+	case "md":
+		write = ck.WriteMarkdown
+	case "txt":
+		write = ck.WriteText
+	case "html":
+		write = ck.WriteHTML
+	case "cooklang":
+		write = ck.WriteCooklang
+	case "jsonld":
+		write = ck.WriteJSONLD
+	case "mealmaster":
+		bundle = ck.WriteMealMasterBundle
+	case "paprika":
+		bundle = ck.WritePaprikaBundle
+	case "json":
+		write = func(w io.Writer, rd *ck.RecipeDetail) error {
+			return writeJSON(w, rd)
+		}
+	default:
+		return usageError("--format must be md, json, txt, html, cooklang, jsonld, mealmaster or paprika")
+	}
+	if bundle != nil && len(args) > 1 {
+		if *input != "" {
+			return usageError("either give recipes or --input")
+		}
+		var recipes []*ck.RecipeDetail
+		for _, i := range args {
+			rd, err := recipe([]string{i}, "", *servings)
+			if err != nil {
+				return err
+			}
+			recipes = append(recipes, rd)
+		}
+		return bundle(stdout, recipes)
+	}
+	rd, err := recipe(args, *input, *servings)
+	if err != nil {
+		return err
+	}
+	if bundle != nil {
+		return bundle(stdout, []*ck.RecipeDetail{rd})
+	}
+	return write(stdout, rd)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
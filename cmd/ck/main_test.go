@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mswift42/ck"
+)
+
+func TestSearchCommand(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"search", "--input", "../../testhtml/bohnen.html", "--min-rating", "4.5"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 || !strings.HasPrefix(lines[0], "ID") {
+		t.Fatalf("Expected a header and 5 rows, got:\n%s", out.String())
+	}
+	if !strings.Contains(lines[1], "Grüne Bohnen mit Speck") || !strings.Contains(lines[1], "25 min") {
+		t.Errorf("Expected the first row to show Grüne Bohnen mit Speck, got: %q", lines[1])
+	}
+
+	out.Reset()
+	err = run([]string{"search", "bohnen", "--json", "--input", "../../testhtml/bohnen.html"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recipes []*ck.Recipe
+	if err := json.Unmarshal(out.Bytes(), &recipes); err != nil {
+		t.Fatal(err)
+	}
+	if len(recipes) != 30 {
+		t.Errorf("Expected 30 recipes, got: %d", len(recipes))
+	}
+}
+
+func TestShowAndExport(t *testing.T) {
+	tests := []struct {
+		args     []string
+		contains string
+	}{
+		{[]string{"show", "--servings", "4"}, "  1000 g       Schupfnudeln (Kühlregal)\n"},
+		{[]string{"export"}, "# Schupfnudel - Bohnen - Pfanne\n"},
+		{[]string{"export", "--format", "txt"}, "Schupfnudel - Bohnen - Pfanne\n====="},
+		{[]string{"export", "--format", "json", "--servings", "4"}, `"servings": 4,`},
+		{[]string{"export", "--format", "html"}, "<h1>Schupfnudel - Bohnen - Pfanne</h1>"},
+		{[]string{"export", "--format", "cooklang"}, ">> title: Schupfnudel - Bohnen - Pfanne\n"},
+		{[]string{"export", "--format", "jsonld"}, "{\n  \"@context\": \"https://schema.org\""},
+		{[]string{"export", "--format", "mealmaster"}, "MMMMM----- Recipe via Meal-Master (tm) v8.05\n"},
+		{[]string{"export", "--format", "paprika"}, "PK"},
+	}
+	for _, i := range tests {
+		var out bytes.Buffer
+		args := append(i.args, "--input", "../../testhtml/schupfnudel.html")
+		if err := run(args, &out); err != nil {
+			t.Fatalf("%v: %v", i.args, err)
+		}
+		if !strings.Contains(out.String(), i.contains) {
+			t.Errorf("Expected %v to print %q, got:\n%s", i.args, i.contains, out.String())
+		}
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, i := range [][]string{
+		nil,
+		{"cook"},
+		{"search"},
+		{"search", "--page", "0", "bohnen"},
+		{"show"},
+		{"show", "1", "2"},
+		{"show", "1", "--input", "x.html"},
+		{"export", "1", "--format", "pdf"},
+		{"export", "1", "2"},
+		{"export", "1", "2", "--format", "paprika", "--input", "x.html"},
+		{"export", "--bogus"},
+	} {
+		err := run(i, &bytes.Buffer{})
+		if _, ok := err.(usageError); !ok {
+			t.Errorf("Expected a usage error for %q, got: %v", i, err)
+		}
+	}
+}
+
+func TestShowCooklang(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ckcook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "pfannkuchen.cook")
+	recipe := ">> title: Pfannkuchen\n>> servings: 2\n\n@Eier{2} mit @Milch{250%ml} verquirlen.\n"
+	if err := ioutil.WriteFile(file, []byte(recipe), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := run([]string{"show", "--input", file, "--servings", "4"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "  500 ml  Milch\n") {
+		t.Errorf("Expected the scaled Cooklang recipe, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,168 @@
+// Command ckserver serves the ck API as a standalone HTTP server.
+//
+// Every flag can also be set with the environment variable given in its
+// usage text; flags take precedence. The disk store, meal plan store and
+// recording fetcher are configured as described in ck.ConfigureFromEnv.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/mswift42/ck"
+)
+
+type config struct {
+	addr            string
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	idleTimeout     time.Duration
+	shutdownTimeout time.Duration
+	upstreamTimeout time.Duration
+	cacheSize       int
+	cacheBytes      int
+	searchPageCap   int
+	searchTTL       time.Duration
+	detailTTL       time.Duration
+	corsOrigin      string
+	userAgent       string
+	rate            float64
+	burst           int
+	maxQueue        int
+}
+
+// parseConfig reads the configuration from args, using the environment
+// as returned by getenv for defaults.
+func parseConfig(args []string, getenv func(string) string) (*config, error) {
+	c := &config{}
+	fs := flag.NewFlagSet("ckserver", flag.ContinueOnError)
+	var errs []error
+	env := func(key, def string) string {
+		if v := getenv(key); v != "" {
+			return v
+		}
+		return def
+	}
+	duration := func(p *time.Duration, name, key, def, usage string) {
+		d, err := time.ParseDuration(env(key, def))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", key, err))
+		}
+		fs.DurationVar(p, name, d, usage+" ($"+key+")")
+	}
+	addr := ":8080"
+	if port := getenv("PORT"); port != "" {
+		addr = ":" + port
+	}
+	fs.StringVar(&c.addr, "addr", env("CK_ADDR", addr), "listen address ($CK_ADDR, or :$PORT)")
+	duration(&c.readTimeout, "read-timeout", "CK_READ_TIMEOUT", "10s", "maximum time to read a request")
+	duration(&c.writeTimeout, "write-timeout", "CK_WRITE_TIMEOUT", "45s", "maximum time to write a response")
+	duration(&c.idleTimeout, "idle-timeout", "CK_IDLE_TIMEOUT", "2m", "keep-alive timeout")
+	duration(&c.shutdownTimeout, "shutdown-timeout", "CK_SHUTDOWN_TIMEOUT", "20s",
+		"time to finish open requests on shutdown")
+	duration(&c.upstreamTimeout, "upstream-timeout", "CK_UPSTREAM_TIMEOUT", "30s",
+		"time budget for the chefkoch requests of a single request")
+	duration(&c.searchTTL, "search-ttl", "CK_SEARCH_TTL", ck.SearchTTL.String(), "cache lifetime of search pages")
+	duration(&c.detailTTL, "detail-ttl", "CK_DETAIL_TTL", ck.DetailTTL.String(), "cache lifetime of recipe pages")
+	integer := func(p *int, name, key string, def int, usage string) {
+		n, err := strconv.Atoi(env(key, strconv.Itoa(def)))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", key, err))
+		}
+		fs.IntVar(p, name, n, usage+" ($"+key+")")
+	}
+	integer(&c.cacheSize, "cache-size", "CK_CACHE_SIZE", 500, "number of pages kept in memory, 0 disables the cache")
+	integer(&c.cacheBytes, "cache-bytes", "CK_CACHE_BYTES", ck.PageCacheBytes,
+		"memory for cached pages in bytes, 0 for no limit")
+	integer(&c.searchPageCap, "search-page-cap", "CK_SEARCH_PAGE_CAP", ck.SearchPageCap,
+		"upstream result pages a filtered search may fetch for one page of matches")
+	polite := ck.NewPoliteFetcher(nil)
+	rate, err := strconv.ParseFloat(env("CK_RATE", strconv.FormatFloat(polite.Rate, 'f', -1, 64)), 64)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("CK_RATE: %v", err))
+	}
+	fs.Float64Var(&c.rate, "rate", rate, "requests per second to each upstream host ($CK_RATE)")
+	integer(&c.burst, "burst", "CK_BURST", polite.Burst, "requests to each upstream host allowed at once")
+	integer(&c.maxQueue, "max-queue", "CK_MAX_QUEUE", polite.MaxQueue,
+		"requests waiting for an upstream host before further ones fail with 429")
+	fs.StringVar(&c.userAgent, "user-agent", env("CK_USER_AGENT", ck.UserAgent),
+		"User-Agent of upstream requests ($CK_USER_AGENT)")
+	fs.StringVar(&c.corsOrigin, "cors-origin", env("CK_CORS_ORIGIN", "*"),
+		"allowed CORS origin, empty to disable CORS ($CK_CORS_ORIGIN)")
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if c.cacheSize < 0 || c.cacheBytes < 0 {
+		return nil, fmt.Errorf("cache size must not be negative")
+	}
+	if c.rate <= 0 || c.burst < 1 || c.maxQueue < 0 {
+		return nil, fmt.Errorf("rate and burst must be positive and max queue not negative")
+	}
+	if c.userAgent == "" {
+		return nil, fmt.Errorf("user agent must not be empty")
+	}
+	if c.searchPageCap < 1 {
+		return nil, fmt.Errorf("search page cap must be at least 1")
+	}
+	return c, nil
+}
+
+func main() {
+	c, err := parseConfig(os.Args[1:], os.Getenv)
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The recording fetcher of ConfigureFromEnv wraps DefaultFetcher, so
+	// it has to be rebuilt first.
+	ck.UserAgent = c.userAgent
+	polite := ck.NewPoliteFetcher(nil)
+	polite.Rate, polite.Burst, polite.MaxQueue = c.rate, c.burst, c.maxQueue
+	ck.DefaultFetcher = ck.NewDefaultFetcher(polite)
+	if err := ck.ConfigureFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	ck.UpstreamTimeout = c.upstreamTimeout
+	ck.SearchTTL, ck.DetailTTL = c.searchTTL, c.detailTTL
+	ck.PageCache = ck.NewSizedLRUCache(c.cacheSize, c.cacheBytes)
+	ck.SearchPageCap = c.searchPageCap
+
+	srv := &http.Server{
+		Addr:         c.addr,
+		Handler:      ck.NewServeMux(c.corsOrigin),
+		ReadTimeout:  c.readTimeout,
+		WriteTimeout: c.writeTimeout,
+		IdleTimeout:  c.idleTimeout,
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+		<-sig
+		log.Print("shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), c.shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+	}()
+	log.Printf("listening on %s", c.addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-done
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mswift42/ck"
+)
+
+func TestParseConfig(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+
+	c, err := parseConfig(nil, getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.addr != ":8080" || c.corsOrigin != "*" || c.cacheSize != 500 || c.searchPageCap != 5 ||
+		c.cacheBytes != 32<<20 || c.rate != 2 || c.burst != 4 || c.maxQueue != 10 ||
+		c.userAgent != ck.UserAgent ||
+		c.upstreamTimeout != 30*time.Second {
+		t.Errorf("Expected the defaults, got: %+v", c)
+	}
+
+	env["PORT"] = "9000"
+	env["CK_CORS_ORIGIN"] = "https://example.com"
+	env["CK_UPSTREAM_TIMEOUT"] = "5s"
+	env["CK_CACHE_SIZE"] = "10"
+	env["CK_SEARCH_PAGE_CAP"] = "3"
+	env["CK_RATE"] = "0.5"
+	env["CK_USER_AGENT"] = "mybot/2.0"
+	c, err = parseConfig([]string{"-cache-size", "20", "-burst", "1"}, getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.addr != ":9000" || c.corsOrigin != "https://example.com" ||
+		c.upstreamTimeout != 5*time.Second || c.cacheSize != 20 || c.searchPageCap != 3 ||
+		c.rate != 0.5 || c.burst != 1 || c.userAgent != "mybot/2.0" {
+		t.Errorf("Expected environment and flags to be applied, got: %+v", c)
+	}
+
+	c, err = parseConfig([]string{"-addr", "localhost:1234", "-cors-origin", ""}, getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.addr != "localhost:1234" || c.corsOrigin != "" {
+		t.Errorf("Expected flags to override the environment, got: %+v", c)
+	}
+
+	env["CK_READ_TIMEOUT"] = "soon"
+	if _, err := parseConfig(nil, getenv); err == nil {
+		t.Error("Expected an invalid CK_READ_TIMEOUT to fail")
+	}
+	delete(env, "CK_READ_TIMEOUT")
+	if _, err := parseConfig([]string{"-cache-size", "-1"}, getenv); err == nil {
+		t.Error("Expected a negative cache size to fail")
+	}
+	if _, err := parseConfig([]string{"-cache-bytes", "-1"}, getenv); err == nil {
+		t.Error("Expected a negative cache memory to fail")
+	}
+	if _, err := parseConfig([]string{"-rate", "0"}, getenv); err == nil {
+		t.Error("Expected a rate of 0 to fail")
+	}
+	if _, err := parseConfig([]string{"-search-page-cap", "0"}, getenv); err == nil {
+		t.Error("Expected a search page cap of 0 to fail")
+	}
+}
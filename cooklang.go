@@ -0,0 +1,471 @@
+package ck
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SourceCooklang marks the fields of a RecipeDetail read by
+// ParseCooklang.
+const SourceCooklang = "cooklang"
+
+// cookware lists the kitchen equipment that WriteCooklang marks with #
+// when a step mentions it.
+var cookware = []string{"Auflaufform", "Backblech", "Backform", "Bräter",
+	"Kasserolle", "Kastenform", "Mixer", "Muffinform", "Pfanne", "Pürierstab",
+	"Schneebesen", "Schüssel", "Sieb", "Springform", "Topf", "Wok"}
+
+var pluralRegex = regexp.MustCompile(`\([^)]*\)`)
+
+// cooklangSpan replaces text[start:end] of a step with its Cooklang
+// markup.
+type cooklangSpan struct {
+	start, end int
+	markup     string
+}
+
+// WriteCooklang writes rd as a Cooklang recipe. Every ingredient is
+// marked as @name{qty%unit} where a step first mentions it, ingredients
+// no step mentions are listed in an extra first step. Timers become
+// ~{qty%unit} and known cookware #name{}. Title, source, servings and
+// times are written as metadata.
+func WriteCooklang(w io.Writer, rd *RecipeDetail) error {
+	var buf bytes.Buffer
+	meta := func(key, value string) {
+		if value = normalizeSpace(value); value != "" && value != "NA" {
+			fmt.Fprintf(&buf, ">> %s: %s\n", key, value)
+		}
+	}
+	metaMinutes := func(key string, m int) {
+		if m > 0 {
+			meta(key, strconv.Itoa(m)+" minutes")
+		}
+	}
+	meta("title", rd.Title)
+	if rd.ID != "" {
+		meta("source", recipeIDURL(rd.ID))
+	}
+	meta("author", rd.Author)
+	if rd.Servings > 0 {
+		meta("servings", strconv.Itoa(rd.Servings))
+	}
+	metaMinutes("prep time", rd.PreptimeMinutes)
+	metaMinutes("cook time", rd.CookingtimeMinutes)
+	metaMinutes("rest time", rd.RestingtimeMinutes)
+	metaMinutes("total time", rd.TotaltimeMinutes)
+	meta("difficulty", rd.Difficulty)
+	meta("image", rd.Image)
+	for _, i := range cooklangSteps(rd) {
+		fmt.Fprintf(&buf, "\n%s\n", i)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func cooklangSteps(rd *RecipeDetail) []string {
+	steps := rd.Steps
+	if len(steps) == 0 {
+		steps = parseSteps(rd.Method)
+	}
+	marked := make([]bool, len(rd.Ingredients))
+	var result []string
+	for _, step := range steps {
+		text := step.Text
+		var spans []cooklangSpan
+		add := func(s cooklangSpan) bool {
+			for _, i := range spans {
+				if s.start < i.end && i.start < s.end {
+					return false
+				}
+			}
+			spans = append(spans, s)
+			return true
+		}
+		for n, i := range rd.Ingredients {
+			name := cooklangName(i)
+			if marked[n] || name == "" {
+				continue
+			}
+			if at := findWord(text, name); at >= 0 {
+				marked[n] = add(cooklangSpan{at, at + len(name), cooklangIngredient(i)})
+			}
+		}
+		for _, i := range step.Timers {
+			if at := strings.Index(text, i.Text); at >= 0 {
+				add(cooklangSpan{at, at + len(i.Text), cooklangTimer(i)})
+			}
+		}
+		for _, i := range cookware {
+			if at := findWord(text, i); at >= 0 {
+				add(cooklangSpan{at, at + len(i), "#" + text[at:at+len(i)] + "{}"})
+			}
+		}
+		sort.Slice(spans, func(a, b int) bool { return spans[a].start > spans[b].start })
+		for _, i := range spans {
+			text = text[:i.start] + i.markup + text[i.end:]
+		}
+		result = append(result, text)
+	}
+	var unmentioned []string
+	for n, i := range rd.Ingredients {
+		if !marked[n] && cooklangName(i) != "" {
+			unmentioned = append(unmentioned, cooklangIngredient(i))
+		}
+	}
+	if len(unmentioned) > 0 {
+		result = append([]string{strings.Join(unmentioned, ", ")}, result...)
+	}
+	return result
+}
+
+// cooklangName is the ingredient name without plural markers such as
+// "Zwiebel(n)", which Cooklang has no use for.
+func cooklangName(i *RecipeIngredient) string {
+	name := i.Name
+	if name == "" {
+		name = i.Ingredient
+	}
+	name = pluralRegex.ReplaceAllString(name, "")
+	return normalizeSpace(strings.NewReplacer("{", "", "}", "").Replace(name))
+}
+
+func cooklangIngredient(i *RecipeIngredient) string {
+	amount := ""
+	if i.Quantity > 0 {
+		amount = cooklangQuantity(i.Quantity)
+		if i.MaxQuantity > 0 {
+			amount += "-" + cooklangQuantity(i.MaxQuantity)
+		}
+		if i.Unit != "" {
+			amount += "%" + i.Unit
+		}
+	}
+	markup := "@" + cooklangName(i) + "{" + amount + "}"
+	if i.Note != "" {
+		markup += "(" + strings.NewReplacer("(", "", ")", "").Replace(i.Note) + ")"
+	}
+	return markup
+}
+
+func cooklangTimer(t *StepTimer) string {
+	unit, div := "minutes", 1
+	if t.Minutes >= 60 && t.Minutes%60 == 0 && t.MaxMinutes%60 == 0 {
+		unit, div = "hours", 60
+	}
+	amount := strconv.Itoa(t.Minutes / div)
+	if t.MaxMinutes > 0 {
+		amount += "-" + strconv.Itoa(t.MaxMinutes/div)
+	}
+	return "~{" + amount + "%" + unit + "}"
+}
+
+// cooklangQuantity writes q as a decimal, or a fraction such as 1/8 for
+// common fractions below one.
+func cooklangQuantity(q float64) string {
+	if q < 1 {
+		for _, i := range []struct {
+			value float64
+			text  string
+		}{{0.125, "1/8"}, {0.25, "1/4"}, {1.0 / 3, "1/3"}, {0.5, "1/2"},
+			{2.0 / 3, "2/3"}, {0.75, "3/4"}} {
+			if math.Abs(q-i.value) < 0.001 {
+				return i.text
+			}
+		}
+	}
+	return strconv.FormatFloat(math.Round(q*1000)/1000, 'f', -1, 64)
+}
+
+// findWord returns the index of the first case-insensitive occurrence of
+// word in text that is not part of a longer word, or -1.
+func findWord(text, word string) int {
+	for i := range text {
+		if i+len(word) > len(text) {
+			break
+		}
+		if !strings.EqualFold(text[i:i+len(word)], word) {
+			continue
+		}
+		before, _ := utf8.DecodeLastRuneInString(text[:i])
+		after, _ := utf8.DecodeRuneInString(text[i+len(word):])
+		// Compounds such as "Butter-Öl" do not count as a mention.
+		if !isWordRune(before) && !isWordRune(after) && before != '-' && after != '-' {
+			return i
+		}
+	}
+	return -1
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+var (
+	blockCommentRegex  = regexp.MustCompile(`(?s)\[-.*?-\]`)
+	cooklangTimeRegex  = regexp.MustCompile(`(?i)^(\d+(?:[.,]\d+)?)(?:\s*-\s*(\d+(?:[.,]\d+)?))?\s*([a-zä.]*)$`)
+	cooklangNameBreaks = "@#~{}.,;:!?"
+)
+
+// ParseCooklang reads a Cooklang recipe into a RecipeDetail. Ingredients
+// are parsed like the chefkoch ingredient table, timers become
+// StepTimers and the metadata keys written by WriteCooklang fill the
+// respective fields. Markup that cannot be parsed is kept as text and
+// reported in Warnings.
+func ParseCooklang(r io.Reader) (*RecipeDetail, error) {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	rd := &RecipeDetail{Sources: make(map[string]string)}
+	var paragraphs []string
+	var current []string
+	// Block comments are replaced by a marker so that lines holding only
+	// comments do not end a paragraph.
+	text := blockCommentRegex.ReplaceAllString(string(body), "\x00")
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if at := strings.Index(line, "--"); at >= 0 {
+			line = line[:at]
+		}
+		line = strings.TrimSpace(strings.Replace(line, "\x00", "", -1))
+		if line == "" && strings.TrimSpace(scanner.Text()) != "" {
+			continue
+		}
+		if strings.HasPrefix(line, ">>") {
+			if colon := strings.Index(line, ":"); colon >= 0 {
+				rd.setCooklangMeta(strings.TrimSpace(line[2:colon]),
+					strings.TrimSpace(line[colon+1:]))
+			}
+			continue
+		}
+		if line == "" {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, strings.Join(current, " "))
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, strings.Join(current, " "))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	var method []string
+	for _, i := range paragraphs {
+		step := rd.parseCooklangStep(i)
+		if step.Text == "" {
+			continue
+		}
+		rd.Steps = append(rd.Steps, step)
+		method = append(method, step.Text)
+	}
+	rd.Method = strings.Join(method, "\n")
+	if len(rd.Ingredients) > 0 {
+		rd.Sources["ingredients"] = SourceCooklang
+	}
+	if rd.Method != "" {
+		rd.Sources["method"] = SourceCooklang
+	}
+	if rd.TotaltimeMinutes == 0 {
+		rd.TotaltimeMinutes = rd.PreptimeMinutes + rd.CookingtimeMinutes +
+			rd.RestingtimeMinutes
+	}
+	if rd.Title == "" && len(rd.Steps) == 0 && len(rd.Ingredients) == 0 {
+		return nil, &ParseError{"", "no recipe found"}
+	}
+	return rd, nil
+}
+
+func (rd *RecipeDetail) setCooklangMeta(key, value string) {
+	field := ""
+	setMinutes := func(display *string, m *int) {
+		*display = value
+		if min, _, ok := cooklangMinutes(value); ok {
+			*m = min
+		} else if d, ok := parseDuration(value); ok {
+			*m = minutes(d)
+		}
+	}
+	switch strings.ToLower(key) {
+	case "title":
+		rd.Title, field = value, "title"
+	case "source", "source.url", "url":
+		rd.ID, field = recipeID(value), "id"
+	case "author", "source.author":
+		rd.Author, field = value, "author"
+	case "servings", "serves", "yield":
+		if f := strings.Fields(value); len(f) > 0 {
+			rd.Servings, _ = strconv.Atoi(f[0])
+		}
+		field = "servings"
+	case "prep time", "time.prep":
+		setMinutes(&rd.Preptime, &rd.PreptimeMinutes)
+		field = "preptime"
+	case "cook time", "time.cook":
+		setMinutes(&rd.Cookingtime, &rd.CookingtimeMinutes)
+		field = "cookingtime"
+	case "rest time":
+		setMinutes(&rd.Restingtime, &rd.RestingtimeMinutes)
+		field = "restingtime"
+	case "total time", "time required", "duration":
+		var display string
+		setMinutes(&display, &rd.TotaltimeMinutes)
+		field = "totaltimeminutes"
+	case "difficulty":
+		rd.Difficulty, field = value, "difficulty"
+	case "image":
+		rd.Image, field = value, "image"
+	default:
+		return
+	}
+	rd.Sources[field] = SourceCooklang
+}
+
+// parseCooklangStep resolves the markup of one step, adding its
+// ingredients to rd, and returns the step with the markup replaced by
+// plain text.
+func (rd *RecipeDetail) parseCooklangStep(s string) *RecipeStep {
+	step := &RecipeStep{}
+	var text strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		if (c != '@' && c != '#' && c != '~') || i+1 == len(s) ||
+			!(s[i+1] == '{' || isWordRune(firstRune(s[i+1:]))) {
+			text.WriteByte(c)
+			i++
+			continue
+		}
+		name, amount, n, ok := cooklangToken(s[i+1:])
+		if !ok {
+			rd.Warnings = append(rd.Warnings, fmt.Sprintf("unclosed %c in %q", c, s))
+			text.WriteByte(c)
+			i++
+			continue
+		}
+		i += 1 + n
+		switch c {
+		case '@':
+			note := ""
+			if strings.HasPrefix(s[i:], "(") {
+				if end := strings.Index(s[i:], ")"); end >= 0 {
+					note = s[i+1 : i+end]
+					i += end + 1
+				}
+			}
+			rd.addCooklangIngredient(name, amount, note)
+			text.WriteString(name)
+		case '#':
+			text.WriteString(name)
+		case '~':
+			qty, unit := splitCooklangAmount(amount)
+			display := strings.TrimSpace(qty + " " + unit)
+			text.WriteString(display)
+			if min, max, ok := cooklangMinutes(display); ok {
+				step.Timers = append(step.Timers, &StepTimer{Text: display,
+					Minutes: min, MaxMinutes: max})
+			}
+		}
+	}
+	step.Text = normalizeSpace(text.String())
+	step.Temperatures = stepTemperatures(step.Text)
+	return step
+}
+
+// cooklangToken reads the name and amount of the markup following a
+// marker. Multi-word names must be followed by braces. n is the number
+// of bytes consumed.
+func cooklangToken(s string) (name, amount string, n int, ok bool) {
+	if brace := strings.Index(s, "{"); brace >= 0 &&
+		!strings.ContainsAny(s[:brace], cooklangNameBreaks) {
+		end := strings.Index(s[brace:], "}")
+		if end < 0 {
+			return "", "", 0, false
+		}
+		return strings.TrimSpace(s[:brace]), strings.TrimSpace(s[brace+1 : brace+end]),
+			brace + end + 1, true
+	}
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !isWordRune(r) && r != '_' && r != '-' {
+			break
+		}
+		n += size
+	}
+	return s[:n], "", n, n > 0
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+func splitCooklangAmount(amount string) (qty, unit string) {
+	if at := strings.Index(amount, "%"); at >= 0 {
+		return strings.TrimSpace(amount[:at]), strings.TrimSpace(amount[at+1:])
+	}
+	return strings.TrimSpace(amount), ""
+}
+
+func (rd *RecipeDetail) addCooklangIngredient(name, amount, note string) {
+	qty, unit := splitCooklangAmount(amount)
+	if qty == "" {
+		for _, i := range rd.Ingredients {
+			if strings.EqualFold(i.Name, name) {
+				return
+			}
+		}
+	}
+	ingredient := name
+	if note != "" {
+		ingredient += " (" + note + ")"
+	}
+	i := newIngredient(strings.TrimSpace(qty+" "+unit), ingredient)
+	if i.Quantity > 0 {
+		i.Amount = formatAmount(i.Quantity, i.MaxQuantity, i.Unit)
+	}
+	rd.Ingredients = append(rd.Ingredients, i)
+}
+
+// cooklangMinutes reads a Cooklang timer or time such as "10 minutes",
+// "1.5 hours" or the range "5-8 min".
+func cooklangMinutes(s string) (min, max int, ok bool) {
+	m := cooklangTimeRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, 0, false
+	}
+	var unit float64
+	switch strings.TrimSuffix(strings.ToLower(m[3]), ".") {
+	case "", "m", "min", "mins", "minute", "minutes", "minuten":
+		unit = 1
+	case "h", "hr", "hrs", "hour", "hours", "std", "stunde", "stunden":
+		unit = 60
+	case "s", "sec", "secs", "second", "seconds", "sekunden":
+		unit = 1.0 / 60
+	default:
+		return 0, 0, false
+	}
+	min = int(math.Ceil(parseFloatComma(m[1]) * unit))
+	if m[2] != "" {
+		max = int(math.Ceil(parseFloatComma(m[2]) * unit))
+	}
+	return min, max, true
+}
+
+func parseFloatComma(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	return f
+}
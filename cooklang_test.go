@@ -0,0 +1,153 @@
+package ck
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const bohnenMitSpeckCooklang = `>> title: Grüne Bohnen mit Speck
+>> source: https://www.chefkoch.de/rezepte/2406611380140966/
+>> author: Fergne
+>> servings: 4
+>> prep time: 25 minutes
+>> cook time: 20 minutes
+>> total time: 45 minutes
+>> difficulty: normal
+>> image: https://static.chefkoch-cdn.de/ck.de/rezepte/240/240661/1135575-960x720-gruene-bohnen-mit-speck.jpg
+
+@Butter{30%g}, @Salz{1%TL}(gestr.), @Pfeffer{}, @Sonnenblumenöl{}(etwas)
+
+Grüne @Bohnen{500%g}(grüne, frisch oder TK) putzen, ca. ~{5%minutes} in Salzwasser ankochen (bei TK nach Anleitung kochen). @Speck{1%Pck.} würfeln und im Butter-Öl Gemisch kross anbraten. Bohnen, Speck und @Bohnenkraut{1%TL} zusammen in einen #Topf{} geben, pfeffern und ~{10-20%minutes} bei kleiner Hitze ziehen lassen, gelegentlich umrühren. Wem es zu kräftig (salzig) ist, einfach weniger Speck nehmen.
+`
+
+func TestWriteCooklang(t *testing.T) {
+	doc := detailDocument("testhtml/gruene_bohnen_mit_speck.html")
+	rd := (&RecipeDetailDocument{doc}).newRecipeDetail()
+	var buf bytes.Buffer
+	if err := WriteCooklang(&buf, rd); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != bohnenMitSpeckCooklang {
+		t.Errorf("Expected:\n%s\ngot:\n%s", bohnenMitSpeckCooklang, buf.String())
+	}
+}
+
+func TestParseCooklang(t *testing.T) {
+	recipe := `-- Familienrezept
+>> title: Pfannkuchen
+>> servings: 4 Stück
+>> cook time: 1 hour
+>> source: https://www.chefkoch.de/rezepte/123/Pfannkuchen.html
+
+Mehl{} @Eier{3} und @Milch{1/2%Liter} in einer #Schüssel verrühren,
+dann @Zucker{1,5%EL}(fein) und eine @Prise Salz{} dazugeben. -- nicht zu viel
+[- Teig darf
+klumpig sein -]
+~Ruhen{30%minutes} lassen.
+
+Im #Ofen bei 180 °C in der #große Pfanne{} ~{1.5%hours} backen. @Eier ergänzen, @Butter{
+`
+	rd, err := ParseCooklang(strings.NewReader(recipe))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rd.Title != "Pfannkuchen" || rd.Servings != 4 || rd.ID != "123" ||
+		rd.CookingtimeMinutes != 60 || rd.TotaltimeMinutes != 60 {
+		t.Errorf("Expected the metadata to be read, got: %q %d %q %d %d", rd.Title,
+			rd.Servings, rd.ID, rd.CookingtimeMinutes, rd.TotaltimeMinutes)
+	}
+	ingredients := []struct {
+		name     string
+		quantity float64
+		unit     string
+		note     string
+	}{
+		{"Eier", 3, "", ""},
+		{"Milch", 0.5, "l", ""},
+		{"Zucker", 1.5, "EL", "fein"},
+		{"Prise Salz", 0, "", ""},
+	}
+	if len(rd.Ingredients) != len(ingredients) {
+		t.Fatalf("Expected %d ingredients, got: %d", len(ingredients), len(rd.Ingredients))
+	}
+	for n, i := range ingredients {
+		got := rd.Ingredients[n]
+		if got.Name != i.name || got.Quantity != i.quantity || got.Unit != i.unit ||
+			got.Note != i.note {
+			t.Errorf("Expected %+v, got: %+v", i, got)
+		}
+	}
+	if rd.Ingredients[1].Amount != "½ l" {
+		t.Errorf("Expected amount ½ l, got: %q", rd.Ingredients[1].Amount)
+	}
+	steps := []string{
+		"Mehl{} Eier und Milch in einer Schüssel verrühren, dann Zucker und eine Prise Salz dazugeben. 30 minutes lassen.",
+		"Im Ofen bei 180 °C in der große Pfanne 1.5 hours backen. Eier ergänzen, @Butter{",
+	}
+	if len(rd.Steps) != len(steps) {
+		t.Fatalf("Expected %d steps, got: %d", len(steps), len(rd.Steps))
+	}
+	for n, i := range steps {
+		if rd.Steps[n].Text != i {
+			t.Errorf("Expected step %q, got: %q", i, rd.Steps[n].Text)
+		}
+	}
+	if len(rd.Steps[0].Timers) != 1 || rd.Steps[0].Timers[0].Minutes != 30 {
+		t.Errorf("Expected a 30 minute timer, got: %v", rd.Steps[0].Timers)
+	}
+	if len(rd.Steps[1].Timers) != 1 || rd.Steps[1].Timers[0].Minutes != 90 {
+		t.Errorf("Expected a 90 minute timer, got: %v", rd.Steps[1].Timers)
+	}
+	if len(rd.Steps[1].Temperatures) != 1 || rd.Steps[1].Temperatures[0].Celsius != 180 {
+		t.Errorf("Expected 180 °C, got: %v", rd.Steps[1].Temperatures)
+	}
+	if len(rd.Warnings) != 1 {
+		t.Errorf("Expected a warning for the unclosed ingredient, got: %q", rd.Warnings)
+	}
+	if _, err := ParseCooklang(strings.NewReader("-- nur ein Kommentar\n")); err == nil {
+		t.Error("Expected an error for a file without recipe")
+	}
+}
+
+func TestCooklangRoundTrip(t *testing.T) {
+	for _, file := range []string{"gruene_bohnen_im_speckmantel.html",
+		"gruene_bohnen_mit_kasseler.html", "gruene_bohnen_mit_speck.html",
+		"schupfnudel.html"} {
+		doc := detailDocument("testhtml/" + file)
+		rd := (&RecipeDetailDocument{doc}).newRecipeDetail()
+		var buf bytes.Buffer
+		if err := WriteCooklang(&buf, rd); err != nil {
+			t.Fatal(err)
+		}
+		back, err := ParseCooklang(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back.Title != rd.Title || back.ID != rd.ID || back.Servings != rd.Servings ||
+			back.PreptimeMinutes != rd.PreptimeMinutes ||
+			back.TotaltimeMinutes != rd.TotaltimeMinutes {
+			t.Errorf("%s: Expected the metadata to survive, got: %+v", file, back)
+		}
+		key := func(i *RecipeIngredient) string {
+			return cooklangName(i) + "|" + formatAmount(i.Quantity, i.MaxQuantity, i.Unit) +
+				"|" + i.Note
+		}
+		want := make(map[string]bool)
+		for _, i := range rd.Ingredients {
+			want[key(i)] = true
+		}
+		for _, i := range back.Ingredients {
+			if !want[key(i)] {
+				t.Errorf("%s: Unexpected ingredient %s", file, key(i))
+			}
+			delete(want, key(i))
+		}
+		if len(want) > 0 {
+			t.Errorf("%s: Expected ingredients %v to survive", file, want)
+		}
+		if len(back.Steps) < len(rd.Steps) {
+			t.Errorf("%s: Expected at least %d steps, got: %d", file, len(rd.Steps), len(back.Steps))
+		}
+	}
+}
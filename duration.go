@@ -0,0 +1,66 @@
+package ck
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	isoDurationRegex = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+	durationRegex    = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*(tage?n?|stunden?|std\.?|h|minuten?|min\.?)`)
+)
+
+// parseDuration reads the preparation times found on chefkoch, such as
+// "ca. 20 Min.", "30 min.", "1 Std. 30 Min." or the ISO-8601 form "PT30M"
+// used in JSON-LD. ok is false for "keine Angabe", "NA" and anything else
+// that does not contain a time.
+func parseDuration(s string) (d time.Duration, ok bool) {
+	s = strings.TrimSpace(s)
+	if m := isoDurationRegex.FindStringSubmatch(s); m != nil && s != "P" {
+		units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+		for ind, i := range m[1:] {
+			if i == "" {
+				continue
+			}
+			n, _ := strconv.Atoi(i)
+			d += time.Duration(n) * units[ind]
+		}
+		return d, true
+	}
+	for _, loc := range durationRegex.FindAllStringSubmatchIndex(s, -1) {
+		// A unit must end the word, so "2 Hähnchen" is no duration.
+		if letterAt(s, loc[1]) {
+			continue
+		}
+		m := []string{s[loc[0]:loc[1]], s[loc[2]:loc[3]], s[loc[4]:loc[5]]}
+		n, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+		if err != nil {
+			continue
+		}
+		unit := time.Minute
+		switch strings.ToLower(m[2])[0] {
+		case 't':
+			unit = 24 * time.Hour
+		case 's', 'h':
+			unit = time.Hour
+		}
+		d += time.Duration(n * float64(unit))
+		ok = true
+	}
+	return d, ok
+}
+
+// letterAt reports whether s has a letter at byte offset i. It stands in
+// for \b, which only knows ASCII letters.
+func letterAt(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return i < len(s) && unicode.IsLetter(r)
+}
+
+func minutes(d time.Duration) int {
+	return int(d / time.Minute)
+}
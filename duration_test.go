@@ -0,0 +1,77 @@
+package ck
+
+import (
+	"testing"
+	"time"
+)
+
+var durations = []struct {
+	s    string
+	want time.Duration
+	ok   bool
+}{
+	{"ca. 20 Min.", 20 * time.Minute, true},
+	{"30 min.", 30 * time.Minute, true},
+	{"1 Std. 30 Min.", 90 * time.Minute, true},
+	{"ca. 2 Stunden", 2 * time.Hour, true},
+	{"1,5 Std.", 90 * time.Minute, true},
+	{"1 Tag", 24 * time.Hour, true},
+	{"PT30M", 30 * time.Minute, true},
+	{"PT1H15M", 75 * time.Minute, true},
+	{"P1DT2H", 26 * time.Hour, true},
+	{"PT0M", 0, true},
+	{"2 h", 2 * time.Hour, true},
+	{"90 Minuten", 90 * time.Minute, true},
+	{"2 Hähnchen", 0, false},
+	{"3 Minzblätter", 0, false},
+	{"keine Angabe", 0, false},
+	{"NA", 0, false},
+	{"", 0, false},
+}
+
+func TestParseDuration(t *testing.T) {
+	for _, i := range durations {
+		d, ok := parseDuration(i.s)
+		if d != i.want || ok != i.ok {
+			t.Errorf("Expected %q to parse to %v, %v, got: %v, %v",
+				i.s, i.want, i.ok, d, ok)
+		}
+	}
+}
+
+func TestRecipeDetailMinutes(t *testing.T) {
+	files := []struct {
+		file        string
+		preptime    int
+		cookingtime int
+		totaltime   int
+	}{
+		{"testhtml/gruene_bohnen_im_speckmantel.html", 30, 15, 45},
+		{"testhtml/schupfnudel.html", 30, 0, 30},
+		{"testhtml/gruene_bohnen_mit_speck.html", 25, 20, 45},
+	}
+	for _, i := range files {
+		rdd := &RecipeDetailDocument{detailDocument(i.file)}
+		rd := rdd.newRecipeDetail()
+		if rd.PreptimeMinutes != i.preptime {
+			t.Errorf("Expected preptime of %s to be %d, got: %d",
+				i.file, i.preptime, rd.PreptimeMinutes)
+		}
+		if rd.CookingtimeMinutes != i.cookingtime {
+			t.Errorf("Expected cookingtime of %s to be %d, got: %d",
+				i.file, i.cookingtime, rd.CookingtimeMinutes)
+		}
+		if rd.TotaltimeMinutes != i.totaltime {
+			t.Errorf("Expected totaltime of %s to be %d, got: %d",
+				i.file, i.totaltime, rd.TotaltimeMinutes)
+		}
+	}
+	doc := detailDocument("testhtml/bohnen.html")
+	for ind, i := range allRecipes(doc)[:len(bohnenrecipes)] {
+		d, _ := parseDuration(bohnenrecipes[ind].preptime)
+		if i.PreptimeMinutes != minutes(d) {
+			t.Errorf("Expected preptime of %q to be %d, got: %d",
+				i.Title, minutes(d), i.PreptimeMinutes)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package ck
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Error codes used in ErrorResponse.
+const (
+	CodeBadRequest          = "bad_request"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodePlanNotFound        = "plan_not_found"
+	CodeUpstreamNotFound    = "upstream_not_found"
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	CodeUpstreamTimeout     = "upstream_timeout"
+	CodeParseFailure        = "parse_failure"
+	CodeNotCached           = "not_cached"
+	CodeDisallowed          = "disallowed"
+	CodeRateLimited         = "rate_limited"
+	CodeInternal            = "internal"
+)
+
+// ErrorResponse is the body of every failed API request.
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestid"`
+}
+
+// BadRequestError is returned for invalid request parameters.
+type BadRequestError struct {
+	Message string
+}
+
+func (e *BadRequestError) Error() string {
+	return e.Message
+}
+
+// MethodNotAllowedError is returned for requests with a method the
+// route does not serve. Allow lists the methods it does, e.g. "POST".
+type MethodNotAllowedError struct {
+	Method string
+	Allow  string
+}
+
+func (e *MethodNotAllowedError) Error() string {
+	return "method " + e.Method + " not allowed, use " + e.Allow
+}
+
+// PlanNotFoundError is returned for meal plans missing from Plans.
+type PlanNotFoundError struct {
+	ID string
+}
+
+func (e *PlanNotFoundError) Error() string {
+	return "meal plan not found: " + e.ID
+}
+
+// UpstreamNotFoundError is returned when chefkoch answers with 404.
+type UpstreamNotFoundError struct {
+	URL string
+}
+
+func (e *UpstreamNotFoundError) Error() string {
+	return "not found upstream: " + e.URL
+}
+
+// UpstreamUnavailableError is returned when chefkoch cannot be reached,
+// does not answer in time or answers with an unexpected status.
+type UpstreamUnavailableError struct {
+	URL        string
+	StatusCode int
+	Err        error
+}
+
+func (e *UpstreamUnavailableError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("upstream unavailable: %s: %v", e.URL, e.Err)
+	}
+	return fmt.Sprintf("upstream unavailable: %s: status %d", e.URL, e.StatusCode)
+}
+
+func (e *UpstreamUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the upstream request ran out of time.
+func (e *UpstreamUnavailableError) Timeout() bool {
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(e.Err, &ne) && ne.Timeout()
+}
+
+// ParseError is returned when an upstream page does not have the
+// expected structure.
+type ParseError struct {
+	URL     string
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing %s: %s", e.URL, e.Message)
+}
+
+// upstreamError wraps a failed fetch of url in UpstreamUnavailableError
+// unless it already carries a more specific type.
+func upstreamError(url string, err error) error {
+	var (
+		notCached   *NotCachedError
+		disallowed  *DisallowedError
+		rateLimited *RateLimitedError
+		notFound    *UpstreamNotFoundError
+		unavailable *UpstreamUnavailableError
+	)
+	if errors.As(err, &notCached) || errors.As(err, &disallowed) ||
+		errors.As(err, &rateLimited) || errors.As(err, &notFound) ||
+		errors.As(err, &unavailable) {
+		return err
+	}
+	return &UpstreamUnavailableError{URL: url, Err: err}
+}
+
+// errorStatus returns the response status and error code for err.
+// Wrapped errors are classified by the typed error they carry.
+func errorStatus(err error) (int, string) {
+	var (
+		badRequest  *BadRequestError
+		notAllowed  *MethodNotAllowedError
+		noPlan      *PlanNotFoundError
+		notFound    *UpstreamNotFoundError
+		unavailable *UpstreamUnavailableError
+		parse       *ParseError
+		notCached   *NotCachedError
+		disallowed  *DisallowedError
+		rateLimited *RateLimitedError
+	)
+	switch {
+	case errors.As(err, &badRequest):
+		return http.StatusBadRequest, CodeBadRequest
+	case errors.As(err, &notAllowed):
+		return http.StatusMethodNotAllowed, CodeMethodNotAllowed
+	case errors.As(err, &noPlan):
+		return http.StatusNotFound, CodePlanNotFound
+	case errors.As(err, &notFound):
+		return http.StatusNotFound, CodeUpstreamNotFound
+	case errors.As(err, &unavailable):
+		if unavailable.Timeout() {
+			return http.StatusGatewayTimeout, CodeUpstreamTimeout
+		}
+		return http.StatusBadGateway, CodeUpstreamUnavailable
+	case errors.As(err, &parse):
+		return http.StatusBadGateway, CodeParseFailure
+	case errors.As(err, &notCached):
+		return http.StatusNotFound, CodeNotCached
+	case errors.As(err, &disallowed):
+		return http.StatusForbidden, CodeDisallowed
+	case errors.As(err, &rateLimited):
+		return http.StatusTooManyRequests, CodeRateLimited
+	}
+	return http.StatusInternalServerError, CodeInternal
+}
+
+// requestID returns the X-Request-Id of r, or a new random one.
+func requestID(r *http.Request) string {
+	if id := r.Header.Get("X-Request-Id"); id != "" {
+		return id
+	}
+	return randomID()
+}
+
+// randomID returns 8 random bytes in hex.
+func randomID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// writeError responds with the status and JSON ErrorResponse for err,
+// telling rate limited clients when to retry.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := errorStatus(err)
+	id := requestID(r)
+	var rl *RateLimitedError
+	if errors.As(err, &rl) {
+		retry := int(math.Ceil(rl.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+	}
+	var mna *MethodNotAllowedError
+	if errors.As(err, &mna) {
+		w.Header().Set("Allow", mna.Allow)
+	}
+	if status >= 500 {
+		log.Printf("%s %s (request %s): %v", r.Method, r.URL, id, err)
+	}
+	body, _ := json.Marshal(&ErrorResponse{Code: code, Message: err.Error(), RequestID: id})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Request-Id", id)
+	w.WriteHeader(status)
+	w.Write(body)
+}
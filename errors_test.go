@@ -0,0 +1,72 @@
+package ck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+// fetcherFunc adapts a func to the Fetcher interface.
+type fetcherFunc func(ctx context.Context, url string) (*Page, error)
+
+func (f fetcherFunc) Fetch(ctx context.Context, url string) (*Page, error) {
+	return f(ctx, url)
+}
+
+func TestErrorResponses(t *testing.T) {
+	defer func(old Fetcher, cache Cache) {
+		DefaultFetcher, PageCache = old, cache
+	}(DefaultFetcher, PageCache)
+	DefaultFetcher = fetcherFunc(func(ctx context.Context, url string) (*Page, error) {
+		switch url {
+		case "https://www.chefkoch.de/rezepte/1/":
+			return &Page{URL: url, StatusCode: 404,
+				Body: []byte("<html><h1>Seite nicht gefunden</h1></html>")}, nil
+		case "https://www.chefkoch.de/rezepte/2/":
+			return &Page{URL: url, StatusCode: 500}, nil
+		}
+		return &Page{URL: url, StatusCode: 200,
+			Body: []byte("<html><body>Wartungsarbeiten</body></html>")}, nil
+	})
+	PageCache = NewLRUCache(10)
+	tests := []struct {
+		query  string
+		status int
+		code   string
+	}{
+		{"recipeurl=1", 404, CodeUpstreamNotFound},
+		{"recipeurl=2", 502, CodeUpstreamUnavailable},
+		{"recipeurl=3", 502, CodeParseFailure},
+		{"recipeurl=3&servings=x", 400, CodeBadRequest},
+		{"recipeurl=http://169.254.169.254/latest/meta-data/", 400, CodeBadRequest},
+	}
+	for _, i := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/recipedetail?"+i.query, nil)
+		r.Header.Set("X-Request-Id", "abc123")
+		detailHandler(w, r)
+		if w.Code != i.status {
+			t.Errorf("Expected status %d for %q, got: %d", i.status, i.query, w.Code)
+		}
+		var resp ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != i.code || resp.RequestID != "abc123" || resp.Message == "" {
+			t.Errorf("Expected code %q with request id abc123, got: %+v", i.code, resp)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	w := httptest.NewRecorder()
+	searchHandler(w, httptest.NewRequest("GET", "/search?query=x&pagenum=0", nil))
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.RequestID) != 16 || w.Header().Get("X-Request-Id") != resp.RequestID {
+		t.Errorf("Expected a generated request id, got: %q", resp.RequestID)
+	}
+}
@@ -0,0 +1,85 @@
+package ck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mswift42/goquery"
+)
+
+// Store, if set, keeps every fetched page on disk. In Offline mode pages
+// are served from Store only and misses, including unreadable entries,
+// fail with a *NotCachedError.
+var (
+	Store   *DiskStore
+	Offline bool
+)
+
+// fetchPage returns the body of url from PageCache or Store, or fetches it
+// and caches successful responses for ttl.
+func fetchPage(ctx context.Context, url string, ttl time.Duration) ([]byte, error) {
+	key := canonicalURL(url)
+	if body, ok := PageCache.Get(key); ok {
+		return body.([]byte), nil
+	}
+	if Store != nil {
+		page, err := Store.Get(url)
+		if err == nil && (Offline || time.Since(page.Fetched) < ttl) {
+			body := []byte(page.Body)
+			PageCache.Set(key, body, ttl)
+			return body, nil
+		}
+		var notCached *NotCachedError
+		if err != nil && !errors.As(err, &notCached) {
+			log.Printf("reading stored %s: %v", key, err)
+		}
+	}
+	if Offline {
+		return nil, &NotCachedError{key}
+	}
+	page, err := DefaultFetcher.Fetch(ctx, url)
+	if err != nil {
+		return nil, upstreamError(url, err)
+	}
+	switch page.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return nil, &UpstreamNotFoundError{url}
+	default:
+		return nil, &UpstreamUnavailableError{URL: url, StatusCode: page.StatusCode}
+	}
+	PageCache.Set(key, page.Body, ttl)
+	if Store != nil {
+		if err := Store.Put(url, page.Body, time.Now()); err != nil {
+			log.Printf("storing %s: %v", key, err)
+		}
+	}
+	return page.Body, nil
+}
+
+func fetchDocument(ctx context.Context, url string, ttl time.Duration) (*goquery.Document, error) {
+	body, err := fetchPage(ctx, url, ttl)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, &ParseError{url, err.Error()}
+	}
+	return doc, nil
+}
+
+func cacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	stats, err := json.Marshal(PageCache.Stats())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Write(stats)
+}
@@ -0,0 +1,120 @@
+package ck
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Page is an upstream response.
+type Page struct {
+	URL        string
+	StatusCode int
+	Body       []byte
+}
+
+// Fetcher retrieves upstream pages. Responses with an error status are
+// returned as a Page, not as an error.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (*Page, error)
+}
+
+// DefaultFetcher is used by the handlers for all upstream requests.
+var DefaultFetcher Fetcher = NewDefaultFetcher(NewPoliteFetcher(nil))
+
+// NewDefaultFetcher returns the fetcher chain of DefaultFetcher, retrying
+// requests to chefkoch through polite, whose Next is set to an
+// HTTPFetcher.
+func NewDefaultFetcher(polite *PoliteFetcher) Fetcher {
+	polite.Next = NewHTTPFetcher(30 * time.Second)
+	return NewRetryFetcher(polite)
+}
+
+// HTTPFetcher fetches pages with a shared http.Client, adding Header to
+// every request. UserAgent is sent unless Header sets one. Clients made
+// by NewHTTPFetcher do not follow redirects to other hosts.
+type HTTPFetcher struct {
+	Client *http.Client
+	Header http.Header
+}
+
+func NewHTTPFetcher(timeout time.Duration) *HTTPFetcher {
+	return &HTTPFetcher{
+		Client: &http.Client{Timeout: timeout, CheckRedirect: sameHostRedirect},
+		Header: make(http.Header)}
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, url string) (*Page, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range f.Header {
+		req.Header[k] = v
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", UserAgent)
+	}
+	res, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{URL: url, StatusCode: res.StatusCode, Body: body}, nil
+}
+
+// RecordingFetcher writes every response of Next to Store. Without Next
+// it replays the stored responses and fails with a *NotCachedError for
+// anything that was not recorded. A store seeded from testhtml/ replays
+// the fixtures.
+type RecordingFetcher struct {
+	Store *DiskStore
+	Next  Fetcher
+}
+
+func (f *RecordingFetcher) Fetch(ctx context.Context, url string) (*Page, error) {
+	if f.Next == nil {
+		stored, err := f.Store.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		status := stored.StatusCode
+		if status == 0 {
+			status = http.StatusOK
+		}
+		return &Page{URL: url, StatusCode: status, Body: []byte(stored.Body)}, nil
+	}
+	page, err := f.Next.Fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	err = f.Store.put(&StoredPage{URL: canonicalURL(url), Fetched: time.Now(),
+		StatusCode: page.StatusCode, Body: string(page.Body)})
+	return page, err
+}
+
+// fetcherFromEnv wraps DefaultFetcher in a RecordingFetcher writing to
+// CK_RECORD_DIR, or replaying from it if CK_REPLAY is set.
+func fetcherFromEnv() error {
+	dir := os.Getenv("CK_RECORD_DIR")
+	if dir == "" {
+		return nil
+	}
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		return err
+	}
+	rf := &RecordingFetcher{Store: store, Next: DefaultFetcher}
+	if os.Getenv("CK_REPLAY") != "" {
+		rf.Next = nil
+	}
+	DefaultFetcher = rf
+	return nil
+}
@@ -0,0 +1,166 @@
+package ck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// useReplayFetcher replays testhtml/ for all upstream requests and returns
+// a function restoring the defaults.
+func useReplayFetcher(t *testing.T) func() {
+	store, remove := seededStore(t)
+	oldFetcher, oldCache := DefaultFetcher, PageCache
+	DefaultFetcher, PageCache = &RecordingFetcher{Store: store}, NewLRUCache(10)
+	return func() {
+		DefaultFetcher, PageCache = oldFetcher, oldCache
+		remove()
+	}
+}
+
+func TestRecordingFetcher(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "<html>"+r.URL.Path+"</html>")
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "ckrecord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &RecordingFetcher{Store: store, Next: NewHTTPFetcher(0)}
+	replayer := &RecordingFetcher{Store: store}
+	for _, i := range []struct {
+		path   string
+		status int
+	}{
+		{"/rezepte/1/a.html", http.StatusOK},
+		{"/missing", http.StatusNotFound},
+	} {
+		recorded, err := recorder.Fetch(context.Background(), ts.URL+i.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replayed, err := replayer.Fetch(context.Background(), ts.URL+i.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replayed.StatusCode != i.status || recorded.StatusCode != i.status {
+			t.Errorf("Expected status %d, got: %d and %d", i.status,
+				recorded.StatusCode, replayed.StatusCode)
+		}
+		if string(replayed.Body) != string(recorded.Body) {
+			t.Errorf("Expected replayed body to be %q, got: %q",
+				recorded.Body, replayed.Body)
+		}
+	}
+	if _, err := replayer.Fetch(context.Background(), ts.URL+"/other"); err == nil {
+		t.Error("Expected an error for a page that was not recorded")
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	defer useReplayFetcher(t)()
+	w := httptest.NewRecorder()
+	searchHandler(w, httptest.NewRequest("GET", "/search?query=bohnen", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	var resp SearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 5922 || resp.Page != 1 || resp.Next != "30" || resp.Prev != "" {
+		t.Errorf("Expected total 5922, page 1 and next 30, got: %d, %d, %q, %q",
+			resp.Total, resp.Page, resp.Next, resp.Prev)
+	}
+	if len(resp.Results) != 30 || resp.Results[0].Title != bohnenrecipes[0].title {
+		t.Errorf("Expected 30 results starting with %q, got: %d",
+			bohnenrecipes[0].title, len(resp.Results))
+	}
+}
+
+func TestFilteredSearchHandler(t *testing.T) {
+	defer useReplayFetcher(t)()
+	defer func(cap int) { SearchPageCap = cap }(SearchPageCap)
+	SearchPageCap = 1
+	w := httptest.NewRecorder()
+	searchHandler(w, httptest.NewRequest("GET",
+		"/search?query=bohnen&difficulty=simpel", nil))
+	var resp SearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 13 || resp.Next != "30" {
+		t.Errorf("Expected 13 results and next 30, got: %d, %q",
+			len(resp.Results), resp.Next)
+	}
+}
+
+func TestFilteredSearchPartial(t *testing.T) {
+	defer useReplayFetcher(t)()
+	defer func(cap int) { SearchPageCap = cap }(SearchPageCap)
+	SearchPageCap = 3
+	w := httptest.NewRecorder()
+	searchHandler(w, httptest.NewRequest("GET",
+		"/search?query=bohnen&difficulty=simpel", nil))
+	var resp SearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 200 || len(resp.Results) != 13 || resp.Next != "30" {
+		t.Errorf("Expected the 13 results of the first page and next 30, got: %d %d, %q",
+			w.Code, len(resp.Results), resp.Next)
+	}
+}
+
+func TestBulkSearchHandler(t *testing.T) {
+	defer useReplayFetcher(t)()
+	w := httptest.NewRecorder()
+	bulkSearchHandler(w, httptest.NewRequest("GET",
+		"/searchpages?query=bohnen&pages=3", nil))
+	var resp BulkSearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 30 {
+		t.Errorf("Expected 30 results, got: %d", len(resp.Results))
+	}
+	if len(resp.Errors) != 2 || resp.Errors[0].Page != 2 || resp.Errors[1].Page != 3 {
+		t.Errorf("Expected errors for pages 2 and 3, got: %+v", resp.Errors)
+	}
+}
+
+func TestDetailHandlerServings(t *testing.T) {
+	defer useReplayFetcher(t)()
+	w := httptest.NewRecorder()
+	detailHandler(w, httptest.NewRequest("GET",
+		"/recipedetail?servings=4&recipeurl="+schupfnudelURL, nil))
+	var rd RecipeDetail
+	if err := json.Unmarshal(w.Body.Bytes(), &rd); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Servings != 4 || rd.Ingredients[0].Quantity != 1000 {
+		t.Errorf("Expected 4 servings with 1000 g Schupfnudeln, got: %d, %v",
+			rd.Servings, rd.Ingredients[0].Quantity)
+	}
+	w = httptest.NewRecorder()
+	detailHandler(w, httptest.NewRequest("GET",
+		"/recipedetail?servings=0&recipeurl="+schupfnudelURL, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got: %d", w.Code)
+	}
+}
@@ -0,0 +1,174 @@
+package ck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// mealTimes are the local start times of the calendar events of each
+// meal, as hour and minute.
+var mealTimes = map[string][2]int{
+	MealBreakfast: {8, 0},
+	MealLunch:     {12, 30},
+	MealDinner:    {19, 0},
+}
+
+var mealLabels = map[string]string{
+	MealBreakfast: "Breakfast",
+	MealLunch:     "Lunch",
+	MealDinner:    "Dinner",
+}
+
+const (
+	mealEventDuration = time.Hour
+	icalTimeLayout    = "20060102T150405"
+)
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// WritePlanICS writes plan as an iCalendar file with one event per slot.
+// recipes holds the fetched recipe of each slot in the order of
+// plan.Slots; for missing entries the slot title is used. Event times
+// have no time zone, so calendars show them at local time.
+func WritePlanICS(w io.Writer, plan *MealPlan, recipes []*RecipeDetail) error {
+	start, err := time.Parse(planDateLayout, plan.Start)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	line := func(name, value string) {
+		writeICSLine(&buf, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//mswift42//ck//EN")
+	line("CALSCALE", "GREGORIAN")
+	if plan.Name != "" {
+		line("X-WR-CALNAME", icalEscaper.Replace(plan.Name))
+	}
+	for n, i := range plan.Slots {
+		var rd *RecipeDetail
+		if n < len(recipes) {
+			rd = recipes[n]
+		}
+		t := mealTimes[i.Meal]
+		begin := start.AddDate(0, 0, i.Day-1).Add(time.Duration(t[0])*time.Hour +
+			time.Duration(t[1])*time.Minute)
+		line("BEGIN", "VEVENT")
+		line("UID", plan.ID+"-"+strconv.Itoa(i.Day)+"-"+i.Meal+"@ck")
+		line("DTSTAMP", plan.Updated.UTC().Format(icalTimeLayout)+"Z")
+		line("DTSTART", begin.Format(icalTimeLayout))
+		line("DTEND", begin.Add(mealEventDuration).Format(icalTimeLayout))
+		line("SUMMARY", icalEscaper.Replace(mealLabels[i.Meal]+": "+slotTitle(i, rd)))
+		line("DESCRIPTION", icalEscaper.Replace(slotDescription(i, rd)))
+		line("URL", recipeIDURL(i.RecipeID))
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func slotTitle(s *MealSlot, rd *RecipeDetail) string {
+	switch {
+	case rd != nil && rd.Title != "":
+		return normalizeSpace(rd.Title)
+	case s.Title != "":
+		return s.Title
+	}
+	return "Recipe " + s.RecipeID
+}
+
+func slotDescription(s *MealSlot, rd *RecipeDetail) string {
+	var lines []string
+	servings := s.Servings
+	if rd != nil {
+		servings = rd.Servings
+	}
+	if servings > 0 {
+		lines = append(lines, strconv.Itoa(servings)+" servings")
+	}
+	if rd != nil {
+		for _, i := range recipeFacts(rd) {
+			switch i.label {
+			case "Preparation", "Cooking", "Resting":
+				lines = append(lines, i.label+": "+i.value)
+			}
+		}
+	}
+	lines = append(lines, recipeIDURL(s.RecipeID))
+	return strings.Join(lines, "\n")
+}
+
+// writeICSLine writes a content line ending in CRLF, folded after 75
+// octets without splitting UTF-8 sequences.
+func writeICSLine(buf *bytes.Buffer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		buf.WriteString(s[:cut])
+		buf.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space.
+		limit = 74
+	}
+	buf.WriteString(s)
+	buf.WriteString("\r\n")
+}
+
+// servePlanCalendar serves a plan as an iCalendar file. Recipe titles
+// are fetched from chefkoch; if that fails the slot titles are used.
+func servePlanCalendar(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != "GET" {
+		writeError(w, r, &MethodNotAllowedError{r.Method, "GET"})
+		return
+	}
+	plan, err := Plans.Get(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), UpstreamTimeout)
+	defer cancel()
+	// Recipes beyond the shopping list limit and those that fail to load
+	// fall back to the slot title.
+	ids := plan.recipeIDs()
+	if len(ids) > ShoppingListMaxRecipes {
+		ids = ids[:ShoppingListMaxRecipes]
+	}
+	byID, err := fetchPlanRecipes(ctx, ids)
+	if err != nil {
+		log.Printf("calendar of plan %s without some recipe details: %v", id, err)
+	}
+	recipes := make([]*RecipeDetail, len(plan.Slots))
+	for n, i := range plan.Slots {
+		if rd := byID[i.RecipeID]; rd != nil {
+			// Only the servings differ between slots; the calendar does not
+			// show ingredients, so the recipe is not scaled.
+			slot := *rd
+			if i.Servings > 0 {
+				slot.Servings = i.Servings
+			}
+			recipes[n] = &slot
+		}
+	}
+	var buf bytes.Buffer
+	if err := WritePlanICS(&buf, plan, recipes); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="plan-%s.ics"`, plan.ID))
+	w.Write(buf.Bytes())
+}
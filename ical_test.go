@@ -0,0 +1,69 @@
+package ck
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePlanICS(t *testing.T) {
+	plan := &MealPlan{ID: "0123456789abcdef", Name: "Woche; mit Bohnen", Start: "2026-10-19",
+		Days: 7, Updated: time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+		Slots: []*MealSlot{
+			{Day: 1, Meal: MealLunch, RecipeID: "1171381223217983", Servings: 4},
+			{Day: 7, Meal: MealDinner, RecipeID: "2406611380140966", Title: "Bohnen, Speck"},
+		}}
+	rd := fixtureDetail("schupfnudel")
+	rd.Scale(4)
+	var buf bytes.Buffer
+	if err := WritePlanICS(&buf, plan, []*RecipeDetail{rd, nil}); err != nil {
+		t.Fatal(err)
+	}
+	want := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//mswift42//ck//EN\r\n" +
+		"CALSCALE:GREGORIAN\r\n" +
+		"X-WR-CALNAME:Woche\\; mit Bohnen\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:0123456789abcdef-1-lunch@ck\r\n" +
+		"DTSTAMP:20261018T093000Z\r\n" +
+		"DTSTART:20261019T123000\r\n" +
+		"DTEND:20261019T133000\r\n" +
+		"SUMMARY:Lunch: Schupfnudel - Bohnen - Pfanne\r\n" +
+		"DESCRIPTION:4 servings\\nPreparation: ca. 30 Min.\\nhttps://www.chefkoch.de/r\r\n" +
+		" ezepte/1171381223217983/\r\n" +
+		"URL:https://www.chefkoch.de/rezepte/1171381223217983/\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:0123456789abcdef-7-dinner@ck\r\n" +
+		"DTSTAMP:20261018T093000Z\r\n" +
+		"DTSTART:20261025T190000\r\n" +
+		"DTEND:20261025T200000\r\n" +
+		"SUMMARY:Dinner: Bohnen\\, Speck\r\n" +
+		"DESCRIPTION:https://www.chefkoch.de/rezepte/2406611380140966/\r\n" +
+		"URL:https://www.chefkoch.de/rezepte/2406611380140966/\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	if buf.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestWriteICSLine(t *testing.T) {
+	var buf bytes.Buffer
+	writeICSLine(&buf, "SUMMARY:"+strings.Repeat("ä", 80))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 folded lines, got: %q", lines)
+	}
+	for n, i := range lines {
+		if len(i) > 75 || (n > 0 && i[0] != ' ') {
+			t.Errorf("Expected line %d to be folded, got: %q", n, i)
+		}
+	}
+	unfolded := strings.Replace(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n ", "", -1)
+	if unfolded != "SUMMARY:"+strings.Repeat("ä", 80) {
+		t.Errorf("Expected folding to keep the text, got: %q", unfolded)
+	}
+}
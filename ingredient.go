@@ -0,0 +1,218 @@
+package ck
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// unitAliases maps the spellings found on chefkoch, lowercased, to the
+// normalized unit.
+var unitAliases = map[string]string{
+	"g":            "g",
+	"gr":           "g",
+	"gr.":          "g",
+	"gramm":        "g",
+	"kg":           "kg",
+	"kilo":         "kg",
+	"kilogramm":    "kg",
+	"ml":           "ml",
+	"milliliter":   "ml",
+	"l":            "l",
+	"liter":        "l",
+	"ltr":          "l",
+	"ltr.":         "l",
+	"el":           "EL",
+	"el.":          "EL",
+	"essl.":        "EL",
+	"esslöffel":    "EL",
+	"eßlöffel":     "EL",
+	"tl":           "TL",
+	"tl.":          "TL",
+	"teel.":        "TL",
+	"teelöffel":    "TL",
+	"bund":         "Bund",
+	"prise":        "Prise",
+	"prisen":       "Prise",
+	"prise(n)":     "Prise",
+	"msp":          "Msp.",
+	"msp.":         "Msp.",
+	"messerspitze": "Msp.",
+	"scheibe":      "Scheibe/n",
+	"scheiben":     "Scheibe/n",
+	"scheibe/n":    "Scheibe/n",
+	"scheibe(n)":   "Scheibe/n",
+	"zehe":         "Zehe(n)",
+	"zehen":        "Zehe(n)",
+	"zehe/n":       "Zehe(n)",
+	"zehe(n)":      "Zehe(n)",
+}
+
+var vulgarFractions = map[rune]float64{
+	'½': 0.5,
+	'⅓': 1.0 / 3,
+	'⅔': 2.0 / 3,
+	'¼': 0.25,
+	'¾': 0.75,
+	'⅛': 0.125,
+}
+
+var (
+	numberRegex = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)(?:/(\d+))?`)
+	// thousandsRegex matches the German "1.000" or "2.500,5", where a dot
+	// followed by exactly three digits separates thousands.
+	thousandsRegex = regexp.MustCompile(`^[1-9]\d{0,2}(?:\.\d{3})+(?:,\d+)?`)
+	fractionRegex  = regexp.MustCompile(`^\s+(\d+)/(\d+)`)
+	rangeRegex     = regexp.MustCompile(`^\s*(?:-|–|bis)\s*`)
+)
+
+// newIngredient builds a RecipeIngredient from the amount and ingredient
+// columns of the ingredient table and parses both into quantity, unit,
+// name and note.
+func newIngredient(amount, ingredient string) *RecipeIngredient {
+	amount = strings.Trim(amount, " \n")
+	ingredient = strings.Trim(ingredient, " \n")
+	ri := &RecipeIngredient{Amount: amount, Ingredient: ingredient}
+	ri.Raw = strings.TrimSpace(normalizeSpace(amount) + " " + normalizeSpace(ingredient))
+	min, max, rest := parseQuantity(normalizeSpace(amount))
+	ri.Quantity = min
+	ri.MaxQuantity = max
+	unit, qualifier := parseUnit(rest)
+	ri.Unit = unit
+	ri.Name, ri.Note = splitNote(normalizeSpace(ingredient))
+	if qualifier != "" {
+		ri.Note = joinNotes(qualifier, ri.Note)
+	}
+	return ri
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// parseQuantity reads a leading quantity such as "500", "1,5", "1/8",
+// "1 ½" or the range "1-2" and returns its bounds together with the
+// remaining text. max is zero unless the quantity is a range.
+func parseQuantity(s string) (min, max float64, rest string) {
+	min, rest, ok := parseNumber(s)
+	if !ok {
+		return 0, 0, strings.TrimSpace(s)
+	}
+	if loc := rangeRegex.FindStringIndex(rest); loc != nil {
+		if upper, after, ok := parseNumber(rest[loc[1]:]); ok {
+			max, rest = upper, after
+		}
+	}
+	return min, max, strings.TrimSpace(rest)
+}
+
+func parseNumber(s string) (float64, string, bool) {
+	var value float64
+	found := false
+	if t := thousandsRegex.FindString(s); t != "" && !digitAt(s, len(t)) {
+		n := strings.Replace(strings.Replace(t, ".", "", -1), ",", ".", 1)
+		value, _ = strconv.ParseFloat(n, 64)
+		return value, s[len(t):], true
+	}
+	if m := numberRegex.FindStringSubmatch(s); m != nil {
+		n, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+		if err != nil {
+			return 0, s, false
+		}
+		if m[2] != "" {
+			d, _ := strconv.ParseFloat(m[2], 64)
+			if d == 0 {
+				return 0, s, false
+			}
+			n = n / d
+			s = s[len(m[0]):]
+		} else {
+			s = s[len(m[0]):]
+			if f := fractionRegex.FindStringSubmatch(s); f != nil {
+				num, _ := strconv.ParseFloat(f[1], 64)
+				d, _ := strconv.ParseFloat(f[2], 64)
+				if d != 0 {
+					n += num / d
+					s = s[len(f[0]):]
+				}
+			}
+		}
+		value = n
+		found = true
+	}
+	trimmed := strings.TrimLeft(s, " ")
+	for r, f := range vulgarFractions {
+		if strings.HasPrefix(trimmed, string(r)) {
+			value += f
+			found = true
+			s = trimmed[len(string(r)):]
+			break
+		}
+	}
+	return value, s, found
+}
+
+func digitAt(s string, i int) bool {
+	return i < len(s) && s[i] >= '0' && s[i] <= '9'
+}
+
+// parseUnit normalizes the unit at the start of s. Anything that follows
+// the unit, or a qualifier such as "n. B." without a quantity, is returned
+// as qualifier. Units that are not in unitAliases are kept as written.
+func parseUnit(s string) (unit, qualifier string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", ""
+	}
+	fields := strings.Fields(s)
+	word := strings.TrimSuffix(fields[0], ",")
+	rest := strings.TrimSpace(strings.TrimPrefix(s, word))
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, ","))
+	if norm, ok := unitAliases[strings.ToLower(word)]; ok {
+		return norm, rest
+	}
+	if isQualifier(s) {
+		return "", s
+	}
+	return word, rest
+}
+
+// isQualifier reports whether an amount without a quantity is a vague
+// measure like "etwas" or "n. B." rather than a unit.
+func isQualifier(s string) bool {
+	switch strings.ToLower(s) {
+	case "n. b.", "n.b.", "etwas", "evtl.", "nach belieben", "reichlich",
+		"wenig", "einige", "viel":
+		return true
+	}
+	return false
+}
+
+// splitNote separates the ingredient name from a preparation note given
+// after a comma or in parentheses, e.g. "Bohnen, frische" or
+// "Käse (Toast-Käse, z.B. Scheibletten)". Plural markers such as
+// "Zwiebel(n)" belong to the name.
+func splitNote(s string) (name, note string) {
+	var notes []string
+	if open := strings.Index(s, " ("); open >= 0 {
+		if close := strings.LastIndex(s, ")"); close > open {
+			notes = append(notes, strings.TrimSpace(s[open+2:close]))
+			s = strings.TrimSpace(s[:open] + s[close+1:])
+		}
+	}
+	if comma := strings.Index(s, ","); comma >= 0 {
+		notes = append([]string{strings.TrimSpace(s[comma+1:])}, notes...)
+		s = s[:comma]
+	}
+	return strings.TrimSpace(s), joinNotes(notes...)
+}
+
+func joinNotes(notes ...string) string {
+	var result []string
+	for _, i := range notes {
+		if i != "" {
+			result = append(result, i)
+		}
+	}
+	return strings.Join(result, ", ")
+}
@@ -0,0 +1,76 @@
+package ck
+
+import "testing"
+
+var parsedIngredients = []struct {
+	amount      string
+	ingredient  string
+	raw         string
+	quantity    float64
+	maxquantity float64
+	unit        string
+	name        string
+	note        string
+}{
+	{"800\u00a0g", "Bohnen, frische", "800 g Bohnen, frische", 800, 0, "g", "Bohnen", "frische"},
+	{"1/8\u00a0Liter", "Fleischbrühe", "1/8 Liter Fleischbrühe", 0.125, 0, "l", "Fleischbrühe", ""},
+	{"4\u00a0Scheibe/n", "Käse (Toast-Käse, z.B. Scheibletten)", "4 Scheibe/n Käse (Toast-Käse, z.B. Scheibletten)",
+		4, 0, "Scheibe/n", "Käse", "Toast-Käse, z.B. Scheibletten"},
+	{"\u00a0n. B.", "Salz und Pfeffer", "n. B. Salz und Pfeffer", 0, 0, "", "Salz und Pfeffer", "n. B."},
+	{"\u00a0", "Olivenöl", "Olivenöl", 0, 0, "", "Olivenöl", ""},
+	{"1\u00a0Becher", "Crème fraîche", "1 Becher Crème fraîche", 1, 0, "Becher", "Crème fraîche", ""},
+	{"1,5 Essl.", "Zucker", "1,5 Essl. Zucker", 1.5, 0, "EL", "Zucker", ""},
+	{"½ TL", "Salz", "½ TL Salz", 0.5, 0, "TL", "Salz", ""},
+	{"1 ½ Tasse", "Reis", "1 ½ Tasse Reis", 1.5, 0, "Tasse", "Reis", ""},
+	{"1 1/2 kg", "Kartoffeln", "1 1/2 kg Kartoffeln", 1.5, 0, "kg", "Kartoffeln", ""},
+	{"1-2", "Zwiebel(n)", "1-2 Zwiebel(n)", 1, 2, "", "Zwiebel(n)", ""},
+	{"2 Zehen", "Knoblauch", "2 Zehen Knoblauch", 2, 0, "Zehe(n)", "Knoblauch", ""},
+	{"1 TL, gestr.", "Salz", "1 TL, gestr. Salz", 1, 0, "TL", "Salz", "gestr."},
+	{"1 Msp.", "Muskat", "1 Msp. Muskat", 1, 0, "Msp.", "Muskat", ""},
+	{"1.000\u00a0g", "Mehl", "1.000 g Mehl", 1000, 0, "g", "Mehl", ""},
+	{"2.500 ml", "Wasser", "2.500 ml Wasser", 2500, 0, "ml", "Wasser", ""},
+	{"1.250,5 g", "Zucker", "1.250,5 g Zucker", 1250.5, 0, "g", "Zucker", ""},
+	{"1.000-1.500 g", "Kartoffeln", "1.000-1.500 g Kartoffeln", 1000, 1500, "g", "Kartoffeln", ""},
+	{"2.5 kg", "Rinderbraten", "2.5 kg Rinderbraten", 2.5, 0, "kg", "Rinderbraten", ""},
+	{"1.2500 kg", "Mehl", "1.2500 kg Mehl", 1.25, 0, "kg", "Mehl", ""},
+	{"etwas", "Sonnenblumenöl", "etwas Sonnenblumenöl", 0, 0, "", "Sonnenblumenöl", "etwas"},
+}
+
+func TestNewIngredient(t *testing.T) {
+	for _, i := range parsedIngredients {
+		ri := newIngredient(i.amount, i.ingredient)
+		if ri.Raw != i.raw {
+			t.Errorf("Expected raw to be %q, got: %q", i.raw, ri.Raw)
+		}
+		if ri.Quantity != i.quantity {
+			t.Errorf("Expected quantity of %q to be %v, got: %v",
+				i.raw, i.quantity, ri.Quantity)
+		}
+		if ri.MaxQuantity != i.maxquantity {
+			t.Errorf("Expected maxquantity of %q to be %v, got: %v",
+				i.raw, i.maxquantity, ri.MaxQuantity)
+		}
+		if ri.Unit != i.unit {
+			t.Errorf("Expected unit of %q to be %q, got: %q", i.raw, i.unit, ri.Unit)
+		}
+		if ri.Name != i.name {
+			t.Errorf("Expected name of %q to be %q, got: %q", i.raw, i.name, ri.Name)
+		}
+		if ri.Note != i.note {
+			t.Errorf("Expected note of %q to be %q, got: %q", i.raw, i.note, ri.Note)
+		}
+	}
+}
+
+func TestJSONLDIngredientQuantities(t *testing.T) {
+	rdd := &RecipeDetailDocument{detailDocument("testhtml/gruene_bohnen_im_speckmantel.html")}
+	ingredients := rdd.jsonld().ingredients()
+	if ingredients[0].Quantity != 800 || ingredients[0].Unit != "g" {
+		t.Errorf("Expected 800 g, got: %v %q", ingredients[0].Quantity,
+			ingredients[0].Unit)
+	}
+	if ingredients[0].Name != "Bohnen" || ingredients[0].Note != "frische" {
+		t.Errorf("Expected Bohnen, frische, got: %q, %q", ingredients[0].Name,
+			ingredients[0].Note)
+	}
+}
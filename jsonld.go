@@ -0,0 +1,338 @@
+package ck
+
+import (
+	"encoding/json"
+	"html"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mswift42/goquery"
+)
+
+// Field sources recorded in RecipeDetail.Sources.
+const (
+	SourceJSONLD   = "jsonld"
+	SourceSelector = "selector"
+)
+
+// ldRecipe is the subset of a schema.org Recipe that chefkoch embeds in
+// its detail pages. Fields whose shape varies between pages are kept raw
+// and decoded with ldStrings.
+type ldRecipe struct {
+	Type               json.RawMessage `json:"@type"`
+	Name               string          `json:"name"`
+	Image              json.RawMessage `json:"image"`
+	Author             json.RawMessage `json:"author"`
+	DatePublished      string          `json:"datePublished"`
+	PrepTime           string          `json:"prepTime"`
+	CookTime           string          `json:"cookTime"`
+	TotalTime          string          `json:"totalTime"`
+	Difficulty         string          `json:"difficulty"`
+	RecipeYield        json.RawMessage `json:"recipeYield"`
+	RecipeIngredient   []string        `json:"recipeIngredient"`
+	RecipeInstructions json.RawMessage `json:"recipeInstructions"`
+	AggregateRating    struct {
+		RatingValue json.RawMessage `json:"ratingValue"`
+		ReviewCount json.RawMessage `json:"reviewCount"`
+	} `json:"aggregateRating"`
+}
+
+// jsonld returns the first schema.org Recipe of the document, or an empty
+// ldRecipe if there is none or it cannot be decoded. The Recipe may be
+// the block itself, an element of a top-level array or a node of an
+// @graph, and its @type may be a list such as ["Recipe", "NewsArticle"].
+func (rdd *RecipeDetailDocument) jsonld() *ldRecipe {
+	result := &ldRecipe{}
+	rdd.doc.Find(`script[type="application/ld+json"]`).EachWithBreak(
+		func(i int, s *goquery.Selection) bool {
+			if ld := ldRecipeNode([]byte(s.Text())); ld != nil {
+				result = ld
+				return false
+			}
+			return true
+		})
+	return result
+}
+
+// ldRecipeNode searches raw, an object or an array, for a Recipe node.
+func ldRecipeNode(raw json.RawMessage) *ldRecipe {
+	var arr []json.RawMessage
+	if err := json.Unmarshal(raw, &arr); err == nil {
+		for _, i := range arr {
+			if ld := ldRecipeNode(i); ld != nil {
+				return ld
+			}
+		}
+		return nil
+	}
+	var node struct {
+		Type  json.RawMessage   `json:"@type"`
+		Graph []json.RawMessage `json:"@graph"`
+	}
+	if err := json.Unmarshal(raw, &node); err != nil {
+		return nil
+	}
+	if containsString(ldStrings(node.Type, ""), "Recipe") {
+		var ld ldRecipe
+		if err := json.Unmarshal(raw, &ld); err != nil {
+			return nil
+		}
+		return &ld
+	}
+	for _, i := range node.Graph {
+		if ld := ldRecipeNode(i); ld != nil {
+			return ld
+		}
+	}
+	return nil
+}
+
+// ldStrings flattens a JSON-LD value that may be a string, a number, an
+// object or an array of those into a list of strings. For objects the
+// value of key is used.
+func ldStrings(raw json.RawMessage, key string) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return []string{s}
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return []string{n.String()}
+	}
+	var arr []json.RawMessage
+	if err := json.Unmarshal(raw, &arr); err == nil {
+		var result []string
+		for _, i := range arr {
+			result = append(result, ldStrings(i, key)...)
+		}
+		return result
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		return ldStrings(obj[key], key)
+	}
+	return nil
+}
+
+func ldFirst(raw json.RawMessage, key string) string {
+	values := ldStrings(raw, key)
+	if len(values) == 0 {
+		return ""
+	}
+	return ldClean(values[0])
+}
+
+func ldClean(s string) string {
+	s = html.UnescapeString(s)
+	return strings.Trim(s, " \n\r\t")
+}
+
+func (ld *ldRecipe) title() string {
+	return ldClean(ld.Name)
+}
+
+func (ld *ldRecipe) rating() string {
+	return ldFirst(ld.AggregateRating.RatingValue, "")
+}
+
+func (ld *ldRecipe) image() string {
+	return ldFirst(ld.Image, "url")
+}
+
+func (ld *ldRecipe) author() string {
+	return ldFirst(ld.Author, "name")
+}
+
+func (ld *ldRecipe) method() string {
+	steps := ldStrings(ld.RecipeInstructions, "text")
+	method := html.UnescapeString(strings.Join(steps, "\n"))
+	method = strings.Replace(method, "\r\n", "\n", -1)
+	return strings.Trim(method, " \n")
+}
+
+// ingredients converts the single-line recipeIngredient entries, e.g.
+// "800,00 g Bohnen , frische", into amount and ingredient. Chefkoch joins
+// quantity, unit and name with single spaces, leaving empty parts blank.
+// The amount is joined with a no-break space like in the ingredient table.
+func (ld *ldRecipe) ingredients() []*RecipeIngredient {
+	var ingredients []*RecipeIngredient
+	for _, i := range ld.RecipeIngredient {
+		line := html.UnescapeString(i)
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		amount := ""
+		ing := line
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) == 2 && (parts[0] == "" || isLDQuantity(parts[0])) {
+			unit, rest := ldUnit(parts[1])
+			amount = ldQuantity(parts[0]) + "\u00a0" + unit
+			ing = rest
+		}
+		ing = strings.Replace(strings.TrimSpace(ing), " ,", ",", -1)
+		ingredients = append(ingredients, newIngredient(amount, ing))
+	}
+	return ingredients
+}
+
+// ldUnit splits the unit off the rest of a recipeIngredient entry. The
+// unit is blank, a single word, a vague measure like "n. B." or a word
+// followed by a qualifier, as in "TL, gestr.".
+func ldUnit(s string) (unit, rest string) {
+	if strings.HasPrefix(s, " ") {
+		return "", s
+	}
+	words := strings.SplitN(s, " ", 3)
+	if len(words) == 3 && isQualifier(words[0]+" "+words[1]) ||
+		len(words) == 3 && strings.HasSuffix(words[0], ",") {
+		return words[0] + " " + words[1], words[2]
+	}
+	if len(words) == 1 {
+		return "", s
+	}
+	return words[0], strings.TrimPrefix(s, words[0]+" ")
+}
+
+// ldQuantity formats the two-decimal quantities of JSON-LD the way the
+// ingredient table shows them: "800,00" as "800" and the rounded thirds
+// and eighths, e.g. "0,13", as the fractions "1/8" they stand for.
+func ldQuantity(s string) string {
+	if !strings.Contains(s, ",") {
+		return s
+	}
+	q, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	if err != nil {
+		return s
+	}
+	whole, frac := math.Modf(q)
+	for _, d := range []float64{3, 8} {
+		n := math.Round(frac * d)
+		if n == 0 || n == d || math.Mod(n, 2) == 0 && d == 8 ||
+			math.Round(n/d*100) != math.Round(frac*100) {
+			continue
+		}
+		fraction := strconv.Itoa(int(n)) + "/" + strconv.Itoa(int(d))
+		if whole == 0 {
+			return fraction
+		}
+		return strconv.Itoa(int(whole)) + " " + fraction
+	}
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ",")
+}
+
+// ldDisplayDuration turns an ISO-8601 duration like "PT90M" into the
+// form shown on the page, "ca. 1 Std. 30 Min.". Missing and zero
+// durations, which chefkoch writes as "PT0M", yield "".
+func ldDisplayDuration(iso string) string {
+	d, ok := parseDuration(iso)
+	if !ok || !strings.HasPrefix(iso, "P") || minutes(d) == 0 {
+		return ""
+	}
+	var parts []string
+	if h := minutes(d) / 60; h > 0 {
+		parts = append(parts, strconv.Itoa(h)+" Std.")
+	}
+	if m := minutes(d) % 60; m > 0 {
+		parts = append(parts, strconv.Itoa(m)+" Min.")
+	}
+	return "ca. " + strings.Join(parts, " ")
+}
+
+func isLDQuantity(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != ',' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// ldOutput is the schema.org Recipe written by WriteJSONLD.
+type ldOutput struct {
+	Context            string          `json:"@context"`
+	Type               string          `json:"@type"`
+	Name               string          `json:"name"`
+	URL                string          `json:"url,omitempty"`
+	Image              string          `json:"image,omitempty"`
+	Author             *ldThing        `json:"author,omitempty"`
+	DatePublished      string          `json:"datePublished,omitempty"`
+	PrepTime           string          `json:"prepTime,omitempty"`
+	CookTime           string          `json:"cookTime,omitempty"`
+	TotalTime          string          `json:"totalTime,omitempty"`
+	RecipeYield        string          `json:"recipeYield,omitempty"`
+	RecipeIngredient   []string        `json:"recipeIngredient"`
+	RecipeInstructions []*ldThing      `json:"recipeInstructions"`
+	AggregateRating    *ldRatingOutput `json:"aggregateRating,omitempty"`
+	Nutrition          *ldThing        `json:"nutrition,omitempty"`
+}
+
+type ldThing struct {
+	Type     string `json:"@type"`
+	Name     string `json:"name,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Calories string `json:"calories,omitempty"`
+}
+
+type ldRatingOutput struct {
+	Type        string `json:"@type"`
+	RatingValue string `json:"ratingValue"`
+	BestRating  string `json:"bestRating"`
+}
+
+func newLDOutput(rd *RecipeDetail) *ldOutput {
+	ld := &ldOutput{Context: "https://schema.org", Type: "Recipe", Name: rd.Title,
+		Image: rd.Image, DatePublished: rd.DatePublished,
+		PrepTime: isoMinutes(rd.PreptimeMinutes), CookTime: isoMinutes(rd.CookingtimeMinutes),
+		TotalTime: isoMinutes(rd.TotaltimeMinutes), RecipeIngredient: []string{},
+		RecipeInstructions: []*ldThing{}}
+	if rd.ID != "" {
+		ld.URL = recipeIDURL(rd.ID)
+	}
+	if rd.Author != "" {
+		ld.Author = &ldThing{Type: "Person", Name: rd.Author}
+	}
+	if rd.Servings > 0 {
+		ld.RecipeYield = strconv.Itoa(rd.Servings)
+	}
+	for _, i := range rd.Ingredients {
+		line := strings.TrimSpace(normalizeSpace(i.Amount) + " " + normalizeSpace(i.Ingredient))
+		ld.RecipeIngredient = append(ld.RecipeIngredient, line)
+	}
+	for _, i := range recipeSteps(rd) {
+		ld.RecipeInstructions = append(ld.RecipeInstructions, &ldThing{Type: "HowToStep", Text: i})
+	}
+	if rd.Rating != "" {
+		ld.AggregateRating = &ldRatingOutput{Type: "AggregateRating", RatingValue: rd.Rating,
+			BestRating: "5"}
+	}
+	if c := normalizeSpace(rd.Calories); c != "" && c != "NA" {
+		ld.Nutrition = &ldThing{Type: "NutritionInformation", Calories: c}
+	}
+	return ld
+}
+
+func isoMinutes(m int) string {
+	if m <= 0 {
+		return ""
+	}
+	return "PT" + strconv.Itoa(m) + "M"
+}
+
+// WriteJSONLD writes rd as a schema.org Recipe in JSON-LD, the markup
+// chefkoch itself embeds in its pages.
+func WriteJSONLD(w io.Writer, rd *RecipeDetail) error {
+	b, err := json.MarshalIndent(newLDOutput(rd), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
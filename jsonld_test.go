@@ -0,0 +1,245 @@
+package ck
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func detailDocument(filename string) *goquery.Document {
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		panic(err)
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(file))
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// detailFixtures are the detail pages in testhtml the exporters are
+// checked against.
+var detailFixtures = []string{
+	"gruene_bohnen_im_speckmantel",
+	"gruene_bohnen_mit_kasseler",
+	"gruene_bohnen_mit_speck",
+	"schupfnudel",
+}
+
+func fixtureDetail(name string) *RecipeDetail {
+	return (&RecipeDetailDocument{detailDocument("testhtml/" + name + ".html")}).newRecipeDetail()
+}
+
+// checkGolden compares got with testdata/golden/name, or rewrites the
+// file when the tests run with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expected %s:\n%s\ngot:\n%s", path, want, got)
+	}
+}
+
+var jsonldDetails = []struct {
+	file          string
+	author        string
+	datePublished string
+	image         string
+	ingredients   int
+}{
+	{
+		"testhtml/gruene_bohnen_im_speckmantel.html",
+		"Spianata",
+		"2006-08-03",
+		"https://static.chefkoch-cdn.de/ck.de/rezepte/56/56345/1124631-960x720-gruene-bohnen-im-speckmantel.jpg",
+		8,
+	},
+	{
+		"testhtml/schupfnudel.html",
+		"miaka-li",
+		"2008-10-05",
+		"https://static.chefkoch-cdn.de/ck.de/rezepte/117/117138/1156413-960x720-schupfnudel-bohnen-pfanne.jpg",
+		8,
+	},
+	{
+		"testhtml/gruene_bohnen_mit_kasseler.html",
+		"Magga",
+		"2003-01-13",
+		"https://static.chefkoch-cdn.de/ck.de/rezepte/10/10362/1135594-960x720-gruene-bohnen-mit-kasseler-geschmort.jpg",
+		7,
+	},
+}
+
+func TestJSONLDRecipeDetail(t *testing.T) {
+	for _, i := range jsonldDetails {
+		rdd := &RecipeDetailDocument{detailDocument(i.file)}
+		rd := rdd.newRecipeDetail()
+		if rd.Author != i.author {
+			t.Errorf("Expected author to be %q, got: %q", i.author, rd.Author)
+		}
+		if rd.DatePublished != i.datePublished {
+			t.Errorf("Expected datepublished to be %q, got: %q",
+				i.datePublished, rd.DatePublished)
+		}
+		if rd.Image != i.image {
+			t.Errorf("Expected image to be %q, got: %q", i.image, rd.Image)
+		}
+		for _, field := range []string{"title", "rating", "method", "author", "preptime",
+			"ingredients"} {
+			if rd.Sources[field] != SourceJSONLD {
+				t.Errorf("Expected source of %s to be %q, got: %q",
+					field, SourceJSONLD, rd.Sources[field])
+			}
+		}
+		if rd.Sources["difficulty"] != SourceSelector {
+			t.Errorf("Expected source of difficulty to be %q, got: %q",
+				SourceSelector, rd.Sources["difficulty"])
+		}
+		ld := rdd.jsonld()
+		if len(ld.ingredients()) != i.ingredients {
+			t.Errorf("Expected %d jsonld ingredients, got: %d",
+				i.ingredients, len(ld.ingredients()))
+		}
+	}
+}
+
+func TestJSONLDShapes(t *testing.T) {
+	for _, name := range []string{"graph", "array", "typelist"} {
+		block, err := ioutil.ReadFile("testdata/jsonld/" + name + ".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := `<html><head><script type="application/ld+json">{"@type": "WebPage"}</script>` +
+			`<script type="application/ld+json">` + string(block) + `</script></head></html>`
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rd := (&RecipeDetailDocument{doc}).newRecipeDetail()
+		if rd.Title != "Grüne Bohnen mit Speck" || rd.Author != "Fergne" ||
+			rd.PreptimeMinutes != 25 || len(rd.Ingredients) != 2 ||
+			rd.Sources["title"] != SourceJSONLD {
+			t.Errorf("%s: Expected the Recipe node to be used, got: %+v", name, rd)
+		}
+	}
+}
+
+func TestJSONLDFallback(t *testing.T) {
+	doc := detailDocument("testhtml/gruene_bohnen_im_speckmantel.html")
+	withld := (&RecipeDetailDocument{doc}).newRecipeDetail()
+	doc.Find(`script[type="application/ld+json"]`).Remove()
+	rd := (&RecipeDetailDocument{doc}).newRecipeDetail()
+	for field, source := range rd.Sources {
+		if source != SourceSelector {
+			t.Errorf("Expected source of %s to be %q, got: %q",
+				field, SourceSelector, source)
+		}
+	}
+	if rd.Title != withld.Title {
+		t.Errorf("Expected title to be %q, got: %q", withld.Title, rd.Title)
+	}
+	if rd.Method != withld.Method {
+		t.Errorf("Expected method to be %q, got: %q", withld.Method, rd.Method)
+	}
+	if rd.Author != withld.Author {
+		t.Errorf("Expected author to be %q, got: %q", withld.Author, rd.Author)
+	}
+	if rd.DatePublished != withld.DatePublished {
+		t.Errorf("Expected datepublished to be %q, got: %q",
+			withld.DatePublished, rd.DatePublished)
+	}
+}
+
+func TestJSONLDIngredients(t *testing.T) {
+	ld := &ldRecipe{RecipeIngredient: []string{
+		"800,00 g Bohnen , frische",
+		"1,00  Knoblauchzehe(n) ",
+		"0,13 Liter Fleischbrühe ",
+		"1,50 TL, gestr. Salz ",
+		" n. B. Salz und Pfeffer ",
+		" etwas Sonnenblumenöl ",
+		"  Olivenöl ",
+	}}
+	want := []RecipeIngredient{
+		{Amount: "800\u00a0g", Ingredient: "Bohnen, frische"},
+		{Amount: "1\u00a0", Ingredient: "Knoblauchzehe(n)"},
+		{Amount: "1/8\u00a0Liter", Ingredient: "Fleischbrühe"},
+		{Amount: "1,5\u00a0TL, gestr.", Ingredient: "Salz"},
+		{Amount: "\u00a0n. B.", Ingredient: "Salz und Pfeffer"},
+		{Amount: "\u00a0etwas", Ingredient: "Sonnenblumenöl"},
+		{Amount: "\u00a0", Ingredient: "Olivenöl"},
+	}
+	got := ld.ingredients()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d ingredients, got: %d", len(want), len(got))
+	}
+	for ind, i := range got {
+		if i.Amount != want[ind].Amount {
+			t.Errorf("Expected amount to be %q, got: %q", want[ind].Amount, i.Amount)
+		}
+		if i.Ingredient != want[ind].Ingredient {
+			t.Errorf("Expected ingredient to be %q, got: %q",
+				want[ind].Ingredient, i.Ingredient)
+		}
+	}
+}
+
+func TestLDDisplayDuration(t *testing.T) {
+	tests := []struct {
+		iso  string
+		want string
+	}{
+		{"PT30M", "ca. 30 Min."},
+		{"PT90M", "ca. 1 Std. 30 Min."},
+		{"PT2H", "ca. 2 Std."},
+		{"PT0M", ""},
+		{"", ""},
+		{"30 Min.", ""},
+	}
+	for _, i := range tests {
+		if got := ldDisplayDuration(i.iso); got != i.want {
+			t.Errorf("Expected %q for %q, got: %q", i.want, i.iso, got)
+		}
+	}
+}
+
+func TestWriteJSONLD(t *testing.T) {
+	for _, name := range detailFixtures {
+		rd := fixtureDetail(name)
+		var buf bytes.Buffer
+		if err := WriteJSONLD(&buf, rd); err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, name+".jsonld", buf.Bytes())
+		page := `<html><head><script type="application/ld+json">` + buf.String() +
+			`</script></head></html>`
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ld := (&RecipeDetailDocument{doc}).jsonld()
+		if ld.title() != rd.Title || ld.servings() != strconv.Itoa(rd.Servings) ||
+			ld.author() != rd.Author || ld.rating() != rd.Rating ||
+			len(ld.ingredients()) != len(rd.Ingredients) {
+			t.Errorf("%s: Expected the JSON-LD to read back, got: %+v", name, ld)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package ck
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// mealMasterUnits maps normalized units to the two letter MealMaster
+// unit codes. Other units are written in front of the ingredient.
+var mealMasterUnits = map[string]string{
+	"g":         "g",
+	"kg":        "kg",
+	"ml":        "ml",
+	"l":         "l",
+	"EL":        "tb",
+	"TL":        "ts",
+	"Prise":     "pn",
+	"Msp.":      "pn",
+	"Bund":      "bn",
+	"Scheibe/n": "sl",
+	"Zehe(n)":   "cl",
+	"Dose":      "cn",
+	"Pck.":      "pk",
+	"Stück":     "ea",
+}
+
+const (
+	mealMasterWidth      = 76
+	mealMasterIngredient = 28
+)
+
+// WriteMealMaster writes rd as a MealMaster recipe.
+func WriteMealMaster(w io.Writer, rd *RecipeDetail) error {
+	return WriteMealMasterBundle(w, []*RecipeDetail{rd})
+}
+
+// WriteMealMasterBundle writes recipes as one MealMaster file. The text
+// is UTF-8 encoded.
+func WriteMealMasterBundle(w io.Writer, recipes []*RecipeDetail) error {
+	var buf bytes.Buffer
+	for n, rd := range recipes {
+		if n > 0 {
+			buf.WriteString("\n")
+		}
+		writeMealMaster(&buf, rd)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeMealMaster(buf *bytes.Buffer, rd *RecipeDetail) {
+	buf.WriteString("MMMMM----- Recipe via Meal-Master (tm) v8.05\n\n")
+	fmt.Fprintf(buf, "      Title: %s\n", normalizeSpace(rd.Title))
+	buf.WriteString(" Categories:\n")
+	if rd.Servings > 0 {
+		fmt.Fprintf(buf, "      Yield: %d servings\n", rd.Servings)
+	}
+	buf.WriteString("\n")
+	for _, i := range rd.Ingredients {
+		qty, unit, text := mealMasterIngredientParts(i)
+		lines := wrapWords(text, mealMasterIngredient)
+		for n, line := range lines {
+			if n == 0 {
+				fmt.Fprintf(buf, "%7s %-2s %s\n", qty, unit, line)
+			} else {
+				fmt.Fprintf(buf, "%7s %-2s -%s\n", "", "", line)
+			}
+		}
+	}
+	buf.WriteString("\n")
+	for _, i := range recipeSteps(rd) {
+		for _, line := range wrapWords(i, mealMasterWidth-2) {
+			fmt.Fprintf(buf, "  %s\n", line)
+		}
+		buf.WriteString("\n")
+	}
+	var notes []string
+	for _, i := range recipeFacts(rd) {
+		switch i.label {
+		case "Preparation", "Cooking", "Resting", "Calories":
+			notes = append(notes, i.label+": "+i.value)
+		}
+	}
+	if rd.ID != "" {
+		source := "Source: " + recipeIDURL(rd.ID)
+		if rd.Author != "" {
+			source = "Recipe by " + normalizeSpace(rd.Author) + ", " + recipeIDURL(rd.ID)
+		}
+		notes = append(notes, source)
+	}
+	for _, i := range notes {
+		fmt.Fprintf(buf, "  %s\n", i)
+	}
+	if len(notes) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("MMMMM\n")
+}
+
+func mealMasterIngredientParts(i *RecipeIngredient) (qty, unit, text string) {
+	text = normalizeSpace(i.Name)
+	if text == "" {
+		text = normalizeSpace(i.Ingredient)
+	}
+	if i.Note != "" {
+		text += ", " + normalizeSpace(i.Note)
+	}
+	if i.Quantity == 0 {
+		return "", "", text
+	}
+	qty = mealMasterQuantity(i.Quantity)
+	if i.MaxQuantity > 0 {
+		qty += "-" + mealMasterQuantity(i.MaxQuantity)
+	}
+	unit, ok := mealMasterUnits[i.Unit]
+	if !ok && i.Unit != "" {
+		text = i.Unit + " " + text
+	}
+	return qty, unit, text
+}
+
+// mealMasterQuantity writes q with fractions such as "1 1/2" where
+// possible and as a decimal otherwise.
+func mealMasterQuantity(q float64) string {
+	whole, frac := math.Modf(q)
+	if frac < 0.001 {
+		return strconv.FormatFloat(whole, 'f', -1, 64)
+	}
+	for _, i := range []struct {
+		value float64
+		text  string
+	}{{0.125, "1/8"}, {0.25, "1/4"}, {1.0 / 3, "1/3"}, {0.5, "1/2"},
+		{2.0 / 3, "2/3"}, {0.75, "3/4"}} {
+		if math.Abs(frac-i.value) < 0.001 {
+			if whole == 0 {
+				return i.text
+			}
+			return strconv.FormatFloat(whole, 'f', -1, 64) + " " + i.text
+		}
+	}
+	return strconv.FormatFloat(math.Round(q*100)/100, 'f', -1, 64)
+}
+
+// wrapWords breaks s into lines of at most width runes, splitting long
+// words only if they do not fit on a line of their own.
+func wrapWords(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		for len([]rune(word)) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			r := []rune(word)
+			lines = append(lines, string(r[:width]))
+			word = string(r[width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case len([]rune(line))+1+len([]rune(word)) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
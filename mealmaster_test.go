@@ -0,0 +1,73 @@
+package ck
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWriteMealMaster(t *testing.T) {
+	var recipes []*RecipeDetail
+	for _, name := range detailFixtures {
+		rd := fixtureDetail(name)
+		recipes = append(recipes, rd)
+		var buf bytes.Buffer
+		if err := WriteMealMaster(&buf, rd); err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, name+".mmf", buf.Bytes())
+	}
+	var buf bytes.Buffer
+	if err := WriteMealMasterBundle(&buf, recipes); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "bundle.mmf", buf.Bytes())
+}
+
+var mealMasterQuantities = []struct {
+	q    float64
+	want string
+}{
+	{1, "1"},
+	{0.5, "1/2"},
+	{1.5, "1 1/2"},
+	{2.0 / 3, "2/3"},
+	{0.125, "1/8"},
+	{1.2, "1.2"},
+	{250, "250"},
+}
+
+func TestMealMasterQuantity(t *testing.T) {
+	for _, i := range mealMasterQuantities {
+		if got := mealMasterQuantity(i.q); got != i.want {
+			t.Errorf("Expected %s for %v, got: %s", i.want, i.q, got)
+		}
+	}
+}
+
+func TestMealMasterIngredientParts(t *testing.T) {
+	tests := []struct {
+		amount, ingredient string
+		want               []string
+	}{
+		{"500 g", "Bohnen, grüne", []string{"500", "g", "Bohnen, grüne"}},
+		{"1 ½ EL", "Öl", []string{"1 1/2", "tb", "Öl"}},
+		{"1-2", "Zwiebel(n)", []string{"1-2", "", "Zwiebel(n)"}},
+		{"2 Becher", "Sahne", []string{"2", "", "Becher Sahne"}},
+		{"", "Salz und Pfeffer", []string{"", "", "Salz und Pfeffer"}},
+	}
+	for _, i := range tests {
+		qty, unit, text := mealMasterIngredientParts(newIngredient(i.amount, i.ingredient))
+		if got := []string{qty, unit, text}; !reflect.DeepEqual(got, i.want) {
+			t.Errorf("Expected %q for %q %q, got: %q", i.want, i.amount, i.ingredient, got)
+		}
+	}
+}
+
+func TestWrapWords(t *testing.T) {
+	got := wrapWords("Schupfnudeln aus dem Kühlregal, in Scheiben", 14)
+	want := []string{"Schupfnudeln", "aus dem", "Kühlregal, in", "Scheiben"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got: %q", want, got)
+	}
+}
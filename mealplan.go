@@ -0,0 +1,398 @@
+package ck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Meals of a plan day, in the order they are eaten.
+const (
+	MealBreakfast = "breakfast"
+	MealLunch     = "lunch"
+	MealDinner    = "dinner"
+)
+
+var meals = []string{MealBreakfast, MealLunch, MealDinner}
+
+// PlanMaxDays limits the number of days of a meal plan. Plans without
+// days cover a week.
+var PlanMaxDays = 31
+
+const (
+	planDefaultDays = 7
+	planDateLayout  = "2006-01-02"
+)
+
+var planIDRegex = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// MealPlan assigns recipes to the meals of Days consecutive days,
+// starting at the date Start, e.g. "2026-10-19".
+type MealPlan struct {
+	ID      string      `json:"id"`
+	Name    string      `json:"name,omitempty"`
+	Start   string      `json:"start"`
+	Days    int         `json:"days"`
+	Slots   []*MealSlot `json:"slots"`
+	Created time.Time   `json:"created"`
+	Updated time.Time   `json:"updated"`
+}
+
+// MealSlot is the recipe planned for a meal. Day counts from 1; Servings
+// zero keeps the servings of the recipe. Title is optional and used when
+// the recipe cannot be fetched.
+type MealSlot struct {
+	Day      int    `json:"day"`
+	Meal     string `json:"meal"`
+	RecipeID string `json:"recipeid"`
+	Title    string `json:"title,omitempty"`
+	Servings int    `json:"servings,omitempty"`
+}
+
+func (p *MealPlan) copy() *MealPlan {
+	c := *p
+	c.Slots = make([]*MealSlot, len(p.Slots))
+	for n, i := range p.Slots {
+		s := *i
+		c.Slots[n] = &s
+	}
+	return &c
+}
+
+func mealIndex(meal string) int {
+	for n, i := range meals {
+		if i == meal {
+			return n
+		}
+	}
+	return -1
+}
+
+// normalize validates p, fills in the default number of days and sorts
+// the slots by day and meal.
+func (p *MealPlan) normalize() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if len([]rune(p.Name)) > 200 {
+		return &BadRequestError{"name must be at most 200 characters"}
+	}
+	if _, err := time.Parse(planDateLayout, p.Start); err != nil {
+		return &BadRequestError{"start must be a date such as 2026-10-19"}
+	}
+	if p.Days == 0 {
+		p.Days = planDefaultDays
+	}
+	if p.Days < 1 || p.Days > PlanMaxDays {
+		return &BadRequestError{"days must be between 1 and " + strconv.Itoa(PlanMaxDays)}
+	}
+	if p.Slots == nil {
+		p.Slots = []*MealSlot{}
+	}
+	seen := make(map[string]bool)
+	for _, i := range p.Slots {
+		if i == nil {
+			return &BadRequestError{"slots must not contain null"}
+		}
+		if err := i.normalize(p.Days); err != nil {
+			return err
+		}
+		key := strconv.Itoa(i.Day) + " " + i.Meal
+		if seen[key] {
+			return &BadRequestError{"day " + strconv.Itoa(i.Day) + " has two " + i.Meal + " slots"}
+		}
+		seen[key] = true
+	}
+	sort.SliceStable(p.Slots, func(x, y int) bool {
+		a, b := p.Slots[x], p.Slots[y]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		return mealIndex(a.Meal) < mealIndex(b.Meal)
+	})
+	return nil
+}
+
+// normalize validates s for a plan of days days and reduces its recipe to
+// the id.
+func (s *MealSlot) normalize(days int) error {
+	if s.Day < 1 || s.Day > days {
+		return &BadRequestError{"day must be between 1 and " + strconv.Itoa(days)}
+	}
+	s.Meal = strings.ToLower(strings.TrimSpace(s.Meal))
+	if mealIndex(s.Meal) < 0 {
+		return &BadRequestError{"meal must be breakfast, lunch or dinner"}
+	}
+	recurl, err := recipeURL(s.RecipeID)
+	if err != nil {
+		return err
+	}
+	s.RecipeID = recipeID(recurl)
+	s.Title = strings.TrimSpace(s.Title)
+	if s.Servings < 0 {
+		return &BadRequestError{"servings must be a positive number"}
+	}
+	return nil
+}
+
+// setSlot replaces the slot for the day and meal of slot, or adds it.
+func (p *MealPlan) setSlot(slot *MealSlot) {
+	for n, i := range p.Slots {
+		if i.Day == slot.Day && i.Meal == slot.Meal {
+			p.Slots[n] = slot
+			return
+		}
+	}
+	p.Slots = append(p.Slots, slot)
+}
+
+// removeSlot removes the slot for day and meal if there is one.
+func (p *MealPlan) removeSlot(day int, meal string) {
+	for n, i := range p.Slots {
+		if i.Day == day && i.Meal == meal {
+			p.Slots = append(p.Slots[:n], p.Slots[n+1:]...)
+			return
+		}
+	}
+}
+
+// recipeIDs returns the distinct recipes of the plan in slot order.
+func (p *MealPlan) recipeIDs() []string {
+	var ids []string
+	for _, i := range p.Slots {
+		if !containsString(ids, i.RecipeID) {
+			ids = append(ids, i.RecipeID)
+		}
+	}
+	return ids
+}
+
+// servings adds up the servings of the slots with recipe id, counting
+// slots without servings with those of the recipe, def.
+func (p *MealPlan) servings(id string, def int) int {
+	total := 0
+	for _, i := range p.Slots {
+		switch {
+		case i.RecipeID != id:
+		case i.Servings > 0:
+			total += i.Servings
+		default:
+			total += def
+		}
+	}
+	return total
+}
+
+// fetchPlanRecipes fetches each recipe of ids once, unscaled, and returns
+// them by id. Recipes that could not be fetched are missing and the first
+// error is returned.
+func fetchPlanRecipes(ctx context.Context, ids []string) (map[string]*RecipeDetail, error) {
+	var recipes []*ShoppingListRecipe
+	for _, i := range ids {
+		recipes = append(recipes, &ShoppingListRecipe{ID: i})
+	}
+	details, err := fetchRecipes(ctx, recipes)
+	byID := make(map[string]*RecipeDetail)
+	for n, i := range details {
+		if i != nil {
+			byID[ids[n]] = i
+		}
+	}
+	return byID, err
+}
+
+// decodeJSONBody decodes the JSON body of r into v, answering with 400 if
+// it cannot be read.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(v); err != nil {
+		return &BadRequestError{"invalid request body: " + err.Error()}
+	}
+	return nil
+}
+
+func writePlan(w http.ResponseWriter, status int, plan *MealPlan) {
+	body, _ := json.Marshal(plan)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// plansHandler serves POST /v1/plans, creating a plan from a MealPlan
+// body. Plans without a start begin today.
+func plansHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, r, &MethodNotAllowedError{r.Method, "POST"})
+		return
+	}
+	var plan MealPlan
+	if err := decodeJSONBody(w, r, &plan); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	plan.ID = randomID()
+	if plan.Start == "" {
+		plan.Start = time.Now().Format(planDateLayout)
+	}
+	if err := plan.normalize(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	plan.Created = time.Now().UTC()
+	plan.Updated = plan.Created
+	if err := Plans.Put(&plan); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/plans/"+plan.ID)
+	writePlan(w, http.StatusCreated, &plan)
+}
+
+// planHandler serves the routes below /v1/plans/{id}:
+//
+//	GET, PUT     /v1/plans/{id}
+//	PUT, DELETE  /v1/plans/{id}/slots/{day}/{meal}
+//	GET          /v1/plans/{id}/shoppinglist
+//	GET          /v1/plans/{id}/calendar.ics
+func planHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/plans/"), "/"), "/")
+	id := parts[0]
+	switch {
+	case id == "":
+		writeError(w, r, &BadRequestError{"plan id is required, e.g. /v1/plans/3f2a9c0e1b4d5a6f"})
+	case len(parts) == 1:
+		servePlan(w, r, id)
+	case len(parts) == 4 && parts[1] == "slots":
+		servePlanSlot(w, r, id, parts[2], parts[3])
+	case len(parts) == 2 && parts[1] == "shoppinglist":
+		servePlanShoppingList(w, r, id)
+	case len(parts) == 2 && parts[1] == "calendar.ics":
+		servePlanCalendar(w, r, id)
+	default:
+		writeError(w, r, &BadRequestError{"unknown plan resource " + r.URL.Path})
+	}
+}
+
+// servePlan returns the plan with id or, for PUT, replaces its name,
+// start, days and slots.
+func servePlan(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case "GET":
+		plan, err := Plans.Get(id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writePlan(w, http.StatusOK, plan)
+	case "PUT":
+		var edit MealPlan
+		if err := decodeJSONBody(w, r, &edit); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		plan, err := Plans.Update(id, func(p *MealPlan) error {
+			if edit.Start == "" {
+				edit.Start = p.Start
+			}
+			if err := edit.normalize(); err != nil {
+				return err
+			}
+			edit.ID, edit.Created, edit.Updated = p.ID, p.Created, time.Now().UTC()
+			*p = edit
+			return nil
+		})
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writePlan(w, http.StatusOK, plan)
+	default:
+		writeError(w, r, &MethodNotAllowedError{r.Method, "GET, PUT"})
+	}
+}
+
+// servePlanSlot sets the slot for day and meal to the MealSlot body or,
+// for DELETE, clears it.
+func servePlanSlot(w http.ResponseWriter, r *http.Request, id, day, meal string) {
+	if r.Method != "PUT" && r.Method != "DELETE" {
+		writeError(w, r, &MethodNotAllowedError{r.Method, "PUT, DELETE"})
+		return
+	}
+	d, err := strconv.Atoi(day)
+	if err != nil {
+		writeError(w, r, &BadRequestError{"day must be a number"})
+		return
+	}
+	var slot MealSlot
+	if r.Method == "PUT" {
+		if err := decodeJSONBody(w, r, &slot); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	meal = strings.ToLower(strings.TrimSpace(meal))
+	slot.Day, slot.Meal = d, meal
+	plan, err := Plans.Update(id, func(p *MealPlan) error {
+		if r.Method == "DELETE" {
+			if mealIndex(meal) < 0 {
+				return &BadRequestError{"meal must be breakfast, lunch or dinner"}
+			}
+			p.removeSlot(d, meal)
+		} else {
+			if err := slot.normalize(p.Days); err != nil {
+				return err
+			}
+			p.setSlot(&slot)
+		}
+		p.Updated = time.Now().UTC()
+		return p.normalize()
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writePlan(w, http.StatusOK, plan)
+}
+
+// servePlanShoppingList serves the shopping list for all slots of a plan
+// in the formats of /shoppinglist.
+func servePlanShoppingList(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != "GET" {
+		writeError(w, r, &MethodNotAllowedError{r.Method, "GET"})
+		return
+	}
+	format, err := requestShoppingListFormat(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	plan, err := Plans.Get(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), UpstreamTimeout)
+	defer cancel()
+	ids := plan.recipeIDs()
+	if len(ids) > ShoppingListMaxRecipes {
+		writeError(w, r, &BadRequestError{"a shopping list takes at most " +
+			strconv.Itoa(ShoppingListMaxRecipes) + " different recipes, the plan has " +
+			strconv.Itoa(len(ids))})
+		return
+	}
+	byID, err := fetchPlanRecipes(ctx, ids)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// A recipe planned for several meals is bought for all of them at once.
+	var details []*RecipeDetail
+	for _, i := range ids {
+		rd := byID[i]
+		rd.Scale(plan.servings(i, rd.Servings))
+		details = append(details, rd)
+	}
+	serveShoppingList(w, r, NewShoppingList(details), format)
+}
@@ -0,0 +1,231 @@
+package ck
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// usePlanStore replaces Plans with an empty MemoryPlanStore and returns a
+// function restoring it.
+func usePlanStore() func() {
+	old := Plans
+	Plans = NewMemoryPlanStore()
+	return func() { Plans = old }
+}
+
+func planRequest(t *testing.T, method, path, body string) (*httptest.ResponseRecorder, *MealPlan) {
+	w := httptest.NewRecorder()
+	NewServeMux("").ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	var plan MealPlan
+	if w.Code/100 == 2 && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(w.Body.Bytes(), &plan); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w, &plan
+}
+
+func TestMealPlanNormalize(t *testing.T) {
+	plan := &MealPlan{Start: "2026-10-19", Slots: []*MealSlot{
+		{Day: 2, Meal: "Lunch", RecipeID: "1"},
+		{Day: 1, Meal: MealDinner, RecipeID: "https://www.chefkoch.de/rezepte/2/Eintopf.html"},
+		{Day: 1, Meal: MealBreakfast, RecipeID: "3", Title: " Müsli "},
+	}}
+	if err := plan.normalize(); err != nil {
+		t.Fatal(err)
+	}
+	if plan.Days != 7 {
+		t.Errorf("Expected a week by default, got: %d days", plan.Days)
+	}
+	var got []string
+	for _, i := range plan.Slots {
+		got = append(got, i.Meal+" "+i.RecipeID+" "+i.Title)
+	}
+	want := "breakfast 3 Müsli,dinner 2 ,lunch 1 "
+	if strings.Join(got, ",") != want {
+		t.Errorf("Expected slots %q, got: %q", want, strings.Join(got, ","))
+	}
+
+	invalid := []*MealPlan{
+		{Start: "19.10.2026"},
+		{Start: "2026-10-19", Days: 32},
+		{Start: "2026-10-19", Slots: []*MealSlot{{Day: 8, Meal: MealLunch, RecipeID: "1"}}},
+		{Start: "2026-10-19", Slots: []*MealSlot{{Day: 1, Meal: "brunch", RecipeID: "1"}}},
+		{Start: "2026-10-19", Slots: []*MealSlot{{Day: 1, Meal: MealLunch, RecipeID: "abc"}}},
+		{Start: "2026-10-19", Slots: []*MealSlot{{Day: 1, Meal: MealLunch, RecipeID: "1", Servings: -2}}},
+		{Start: "2026-10-19", Slots: []*MealSlot{{Day: 1, Meal: MealLunch, RecipeID: "1"},
+			{Day: 1, Meal: MealLunch, RecipeID: "2"}}},
+		{Start: "2026-10-19", Slots: []*MealSlot{nil}},
+	}
+	for _, i := range invalid {
+		if err := i.normalize(); err == nil {
+			t.Errorf("Expected %+v to be rejected", i)
+		} else if _, ok := err.(*BadRequestError); !ok {
+			t.Errorf("Expected a BadRequestError, got: %v", err)
+		}
+	}
+}
+
+func TestMealPlanHandlers(t *testing.T) {
+	defer usePlanStore()()
+	defer useReplayFetcher(t)()
+
+	w, plan := planRequest(t, "POST", "/v1/plans", `{"name": "Bohnenwoche", "start": "2026-10-19",
+		"slots": [{"day": 1, "meal": "dinner", "recipeid": "1171381223217983", "servings": 4}]}`)
+	if w.Code != 201 || plan.ID == "" || w.Header().Get("Location") != "/v1/plans/"+plan.ID {
+		t.Fatalf("Expected the plan to be created, got: %d %s", w.Code, w.Body.String())
+	}
+	path := "/v1/plans/" + plan.ID
+	w, got := planRequest(t, "GET", path, "")
+	if w.Code != 200 || got.Name != "Bohnenwoche" || got.Days != 7 || len(got.Slots) != 1 {
+		t.Errorf("Expected the created plan, got: %d %s", w.Code, w.Body.String())
+	}
+
+	w, got = planRequest(t, "PUT", path+"/slots/3/lunch", `{"recipeid": "2406611380140966"}`)
+	if w.Code != 200 || len(got.Slots) != 2 || got.Slots[1].Day != 3 || got.Slots[1].Meal != MealLunch {
+		t.Errorf("Expected a lunch slot on day 3, got: %d %s", w.Code, w.Body.String())
+	}
+	w, got = planRequest(t, "PUT", path+"/slots/3/lunch",
+		`{"recipeid": "2406611380140966", "servings": 2}`)
+	if w.Code != 200 || len(got.Slots) != 2 || got.Slots[1].Servings != 2 {
+		t.Errorf("Expected the lunch slot to be replaced, got: %d %s", w.Code, w.Body.String())
+	}
+	w, got = planRequest(t, "PUT", path+"/slots/2/breakfast", `{"recipeid": "563451154612271"}`)
+	if w.Code != 200 || len(got.Slots) != 3 || got.Slots[1].Day != 2 {
+		t.Errorf("Expected the slots to stay sorted, got: %d %s", w.Code, w.Body.String())
+	}
+	w, got = planRequest(t, "DELETE", path+"/slots/2/breakfast", "")
+	if w.Code != 200 || len(got.Slots) != 2 {
+		t.Errorf("Expected the breakfast slot to be removed, got: %d %s", w.Code, w.Body.String())
+	}
+	w, got = planRequest(t, "PUT", path+"/slots/1/Dinner", `{"recipeid": "563451154612271"}`)
+	if w.Code != 200 || len(got.Slots) != 2 || got.Slots[0].Meal != MealDinner ||
+		got.Slots[0].RecipeID != "563451154612271" {
+		t.Errorf("Expected the dinner slot to be replaced, got: %d %s", w.Code, w.Body.String())
+	}
+	w, got = planRequest(t, "DELETE", path+"/slots/1/Dinner", "")
+	if w.Code != 200 || len(got.Slots) != 1 || got.Slots[0].Day != 3 {
+		t.Errorf("Expected the dinner slot to be removed, got: %d %s", w.Code, w.Body.String())
+	}
+	w, got = planRequest(t, "PUT", path+"/slots/1/dinner", `{"recipeid": "1171381223217983"}`)
+	if w.Code != 200 || len(got.Slots) != 2 {
+		t.Errorf("Expected the dinner slot to be set again, got: %d %s", w.Code, w.Body.String())
+	}
+
+	w, got = planRequest(t, "PUT", path, `{"name": "Zwei Tage", "days": 2,
+		"slots": [{"day": 2, "meal": "dinner", "recipeid": "1171381223217983", "servings": 4},
+		{"day": 1, "meal": "dinner", "recipeid": "2406611380140966"}]}`)
+	if w.Code != 200 || got.Name != "Zwei Tage" || got.Start != "2026-10-19" || got.ID != plan.ID ||
+		!got.Created.Equal(plan.Created) || len(got.Slots) != 2 || got.Slots[0].Day != 1 {
+		t.Errorf("Expected the plan to be replaced, got: %d %s", w.Code, w.Body.String())
+	}
+
+	w, _ = planRequest(t, "GET", path+"/shoppinglist?format=md", "")
+	if w.Code != 200 || !strings.Contains(strings.Replace(w.Body.String(), "\u00a0", " ", -1),
+		"- [ ] 1 kg Bohnen (") {
+		t.Errorf("Expected the shopping list of the plan, got: %d\n%s", w.Code, w.Body.String())
+	}
+
+	w, _ = planRequest(t, "GET", path+"/calendar.ics", "")
+	body := w.Body.String()
+	if w.Code != 200 || w.Header().Get("Content-Type") != "text/calendar; charset=utf-8" ||
+		strings.Count(body, "BEGIN:VEVENT") != 2 ||
+		!strings.Contains(body, "SUMMARY:Dinner: Grüne Bohnen mit Speck\r\n") ||
+		!strings.Contains(body, "DTSTART:20261020T190000\r\n") {
+		t.Errorf("Expected the calendar of the plan, got: %d\n%s", w.Code, body)
+	}
+	if d := w.Header().Get("Content-Disposition"); d != `attachment; filename="plan-`+plan.ID+`.ics"` {
+		t.Errorf("Expected the calendar as download, got: %q", d)
+	}
+}
+
+func TestMealPlanRecipes(t *testing.T) {
+	defer usePlanStore()()
+	defer useReplayFetcher(t)()
+	_, plan := planRequest(t, "POST", "/v1/plans", `{"start": "2026-10-19", "slots": [
+		{"day": 1, "meal": "dinner", "recipeid": "1171381223217983"},
+		{"day": 2, "meal": "lunch", "recipeid": "1"},
+		{"day": 3, "meal": "dinner", "recipeid": "1171381223217983", "servings": 4}]}`)
+	path := "/v1/plans/" + plan.ID
+
+	w, _ := planRequest(t, "GET", path+"/calendar.ics", "")
+	body := w.Body.String()
+	if w.Code != 200 || strings.Count(body, "SUMMARY:Dinner: Schupfnudel - Bohnen - Pfanne\r\n") != 2 ||
+		!strings.Contains(body, "SUMMARY:Lunch: Recipe 1\r\n") ||
+		!strings.Contains(body, "DESCRIPTION:2 servings\\n") ||
+		!strings.Contains(body, "DESCRIPTION:4 servings\\n") {
+		t.Errorf("Expected the missing recipe to fall back per slot, got: %d\n%s", w.Code, body)
+	}
+
+	_, plan = planRequest(t, "PUT", path, `{"start": "2026-10-19", "slots": [
+		{"day": 1, "meal": "dinner", "recipeid": "1171381223217983"},
+		{"day": 3, "meal": "dinner", "recipeid": "1171381223217983", "servings": 4}]}`)
+	w = httptest.NewRecorder()
+	NewServeMux("").ServeHTTP(w, httptest.NewRequest("GET", path+"/shoppinglist", nil))
+	var list ShoppingList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Recipes) != 1 || list.Recipes[0].Servings != 6 {
+		t.Errorf("Expected the recipe once for 6 servings, got: %d %s", w.Code, w.Body.String())
+	}
+
+	var slots []string
+	for day := 1; day <= 11; day++ {
+		for _, meal := range []string{"breakfast", "lunch", "dinner"} {
+			slots = append(slots, fmt.Sprintf(`{"day": %d, "meal": %q, "recipeid": "%d"}`,
+				day, meal, 100+len(slots)))
+		}
+	}
+	planRequest(t, "PUT", path, `{"days": 14, "slots": [`+strings.Join(slots, ",")+`]}`)
+	w, _ = planRequest(t, "GET", path+"/shoppinglist", "")
+	if w.Code != 400 {
+		t.Errorf("Expected too many recipes to be rejected, got: %d %s", w.Code, w.Body.String())
+	}
+	w, _ = planRequest(t, "GET", path+"/calendar.ics", "")
+	if w.Code != 200 || strings.Count(w.Body.String(), "BEGIN:VEVENT") != 33 {
+		t.Errorf("Expected the calendar of all slots, got: %d", w.Code)
+	}
+}
+
+func TestMealPlanHandlerErrors(t *testing.T) {
+	defer usePlanStore()()
+	_, plan := planRequest(t, "POST", "/v1/plans", `{"start": "2026-10-19", "days": 2}`)
+	path := "/v1/plans/" + plan.ID
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"GET", "/v1/plans", "", 405, CodeMethodNotAllowed},
+		{"POST", "/v1/plans", `{"start": "tomorrow"}`, 400, CodeBadRequest},
+		{"POST", "/v1/plans", `[]`, 400, CodeBadRequest},
+		{"GET", "/v1/plans/0000000000000000", "", 404, CodePlanNotFound},
+		{"GET", "/v1/plans/0000000000000000/calendar.ics", "", 404, CodePlanNotFound},
+		{"DELETE", path, "", 405, CodeMethodNotAllowed},
+		{"PUT", path, `{"days": 40}`, 400, CodeBadRequest},
+		{"PUT", path + "/slots/3/lunch", `{"recipeid": "1"}`, 400, CodeBadRequest},
+		{"PUT", path + "/slots/x/lunch", `{"recipeid": "1"}`, 400, CodeBadRequest},
+		{"DELETE", path + "/slots/1/tea", "", 400, CodeBadRequest},
+		{"GET", path + "/slots/1/lunch", "", 405, CodeMethodNotAllowed},
+		{"GET", path + "/shoppinglist?format=pdf", "", 400, CodeBadRequest},
+		{"GET", path + "/pdf", "", 400, CodeBadRequest},
+	}
+	for _, i := range tests {
+		w, _ := planRequest(t, i.method, i.path, i.body)
+		var resp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != i.status || resp.Code != i.code {
+			t.Errorf("Expected %d %s for %s %s, got: %d %s", i.status, i.code, i.method,
+				i.path, w.Code, w.Body.String())
+		}
+	}
+	if _, got := planRequest(t, "GET", path, ""); got.Days != 2 || len(got.Slots) != 0 {
+		t.Errorf("Expected failed edits to leave the plan unchanged, got: %+v", got)
+	}
+}
@@ -0,0 +1,152 @@
+package ck
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// paprikaRecipe is a recipe in the format of the Paprika recipe manager.
+// A .paprikarecipes archive is a zip file holding one gzip compressed
+// JSON document per recipe.
+type paprikaRecipe struct {
+	UID             string   `json:"uid"`
+	Name            string   `json:"name"`
+	Directions      string   `json:"directions"`
+	Ingredients     string   `json:"ingredients"`
+	Servings        string   `json:"servings"`
+	Rating          int      `json:"rating"`
+	Difficulty      string   `json:"difficulty"`
+	PrepTime        string   `json:"prep_time"`
+	CookTime        string   `json:"cook_time"`
+	TotalTime       string   `json:"total_time"`
+	Source          string   `json:"source"`
+	SourceURL       string   `json:"source_url"`
+	ImageURL        string   `json:"image_url"`
+	PhotoData       *string  `json:"photo_data"`
+	Notes           string   `json:"notes"`
+	NutritionalInfo string   `json:"nutritional_info"`
+	Categories      []string `json:"categories"`
+	Created         string   `json:"created"`
+	Hash            string   `json:"hash"`
+}
+
+func newPaprikaRecipe(rd *RecipeDetail) *paprikaRecipe {
+	p := &paprikaRecipe{Name: normalizeSpace(rd.Title), Difficulty: rd.Difficulty,
+		ImageURL: rd.Image, Categories: []string{}}
+	var ingredients []string
+	for _, i := range rd.Ingredients {
+		ingredients = append(ingredients,
+			strings.TrimSpace(normalizeSpace(i.Amount)+" "+normalizeSpace(i.Ingredient)))
+	}
+	p.Ingredients = strings.Join(ingredients, "\n")
+	p.Directions = strings.Join(recipeSteps(rd), "\n\n")
+	if rd.Servings > 0 {
+		p.Servings = strconv.Itoa(rd.Servings)
+	}
+	if r, err := strconv.ParseFloat(rd.Rating, 64); err == nil {
+		p.Rating = int(math.Round(r))
+	}
+	times := func(display string, minutes int) string {
+		if display = normalizeSpace(display); display != "" && display != "NA" {
+			return display
+		}
+		if minutes > 0 {
+			return strconv.Itoa(minutes) + " Min."
+		}
+		return ""
+	}
+	p.PrepTime = times(rd.Preptime, rd.PreptimeMinutes)
+	p.CookTime = times(rd.Cookingtime, rd.CookingtimeMinutes)
+	p.TotalTime = times("", rd.TotaltimeMinutes)
+	if rd.ID != "" {
+		p.Source = "chefkoch.de"
+		p.SourceURL = recipeIDURL(rd.ID)
+	}
+	if rd.Author != "" {
+		p.Notes = "Recipe by " + normalizeSpace(rd.Author)
+	}
+	if c := normalizeSpace(rd.Calories); c != "" && c != "NA" {
+		p.NutritionalInfo = c
+	}
+	if rd.DatePublished != "" {
+		p.Created = rd.DatePublished + " 00:00:00"
+	}
+	key := p.SourceURL
+	if key == "" {
+		key = p.Name
+	}
+	p.UID = paprikaUID(key)
+	sum := sha256.Sum256([]byte(p.Name + "\n" + p.Ingredients + "\n" + p.Directions))
+	p.Hash = strings.ToUpper(hex.EncodeToString(sum[:]))
+	return p
+}
+
+// paprikaUID derives a stable UUID-formatted id from key, so that
+// importing a recipe twice updates it instead of adding a copy.
+func paprikaUID(key string) string {
+	sum := sha1.Sum([]byte(key))
+	h := strings.ToUpper(hex.EncodeToString(sum[:16]))
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// WritePaprika writes rd as a .paprikarecipes archive.
+func WritePaprika(w io.Writer, rd *RecipeDetail) error {
+	return WritePaprikaBundle(w, []*RecipeDetail{rd})
+}
+
+// WritePaprikaBundle writes recipes as one .paprikarecipes archive.
+func WritePaprikaBundle(w io.Writer, recipes []*RecipeDetail) error {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	names := make(map[string]int)
+	for _, rd := range recipes {
+		body, err := json.Marshal(newPaprikaRecipe(rd))
+		if err != nil {
+			return err
+		}
+		name := paprikaFilename(rd.Title)
+		names[name]++
+		if n := names[name]; n > 1 {
+			name = fmt.Sprintf("%s (%d)", name, n)
+		}
+		entry, err := archive.Create(name + ".paprikarecipe")
+		if err != nil {
+			return err
+		}
+		gz := gzip.NewWriter(entry)
+		if _, err := gz.Write(body); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func paprikaFilename(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, normalizeSpace(title))
+	if name == "" {
+		name = "Recipe"
+	}
+	return name
+}
@@ -0,0 +1,103 @@
+package ck
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+// paprikaEntries unpacks a .paprikarecipes archive into its file names
+// and decompressed JSON documents.
+func paprikaEntries(t *testing.T, archive []byte) ([]string, [][]byte) {
+	r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	var docs [][]byte
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := ioutil.ReadAll(gz)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, f.Name)
+		docs = append(docs, doc)
+	}
+	return names, docs
+}
+
+func TestWritePaprika(t *testing.T) {
+	for _, name := range detailFixtures {
+		rd := fixtureDetail(name)
+		var buf bytes.Buffer
+		if err := WritePaprika(&buf, rd); err != nil {
+			t.Fatal(err)
+		}
+		names, docs := paprikaEntries(t, buf.Bytes())
+		if len(docs) != 1 || names[0] != rd.Title+".paprikarecipe" {
+			t.Fatalf("Expected one entry for %s, got: %q", rd.Title, names)
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, docs[0], "", "  "); err != nil {
+			t.Fatal(err)
+		}
+		indented.WriteString("\n")
+		checkGolden(t, name+".paprika.json", indented.Bytes())
+		var again bytes.Buffer
+		WritePaprika(&again, rd)
+		if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+			t.Errorf("Expected the archive for %s to be reproducible", name)
+		}
+	}
+}
+
+func TestWritePaprikaBundle(t *testing.T) {
+	speck := fixtureDetail("gruene_bohnen_mit_speck")
+	recipes := []*RecipeDetail{fixtureDetail("schupfnudel"), speck, speck}
+	var buf bytes.Buffer
+	if err := WritePaprikaBundle(&buf, recipes); err != nil {
+		t.Fatal(err)
+	}
+	names, docs := paprikaEntries(t, buf.Bytes())
+	want := []string{"Schupfnudel - Bohnen - Pfanne.paprikarecipe",
+		"Grüne Bohnen mit Speck.paprikarecipe", "Grüne Bohnen mit Speck (2).paprikarecipe"}
+	if len(names) != len(want) {
+		t.Fatalf("Expected entries %q, got: %q", want, names)
+	}
+	for n, i := range want {
+		if names[n] != i {
+			t.Errorf("Expected entry %q, got: %q", i, names[n])
+		}
+		var p paprikaRecipe
+		if err := json.Unmarshal(docs[n], &p); err != nil || p.Name != recipes[n].Title {
+			t.Errorf("Expected entry %q to hold %s, got: %v", i, recipes[n].Title, err)
+		}
+	}
+}
+
+func TestPaprikaFilename(t *testing.T) {
+	tests := []struct {
+		title, want string
+	}{
+		{"Nudeln 1/2", "Nudeln 1_2"},
+		{"  Was ist: \"Quiche\"? ", "Was ist_ _Quiche__"},
+		{"", "Recipe"},
+	}
+	for _, i := range tests {
+		if got := paprikaFilename(i.title); got != i.want {
+			t.Errorf("Expected %q for %q, got: %q", i.want, i.title, got)
+		}
+	}
+}
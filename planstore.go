@@ -0,0 +1,166 @@
+package ck
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// PlanStore keeps meal plans by id.
+type PlanStore interface {
+	// Get returns the plan with id or a *PlanNotFoundError.
+	Get(id string) (*MealPlan, error)
+	// Put stores plan under its id, replacing an existing plan.
+	Put(plan *MealPlan) error
+	// Update applies edit to the plan with id and stores the result
+	// unless edit fails. Concurrent updates of a plan do not interleave.
+	Update(id string, edit func(*MealPlan) error) (*MealPlan, error)
+}
+
+// Plans is used by the meal plan handlers. ConfigureFromEnv replaces it
+// with a DiskPlanStore if CK_PLAN_DIR is set.
+var Plans PlanStore = NewMemoryPlanStore()
+
+// MemoryPlanStore is a PlanStore that loses its plans on restart.
+type MemoryPlanStore struct {
+	mu    sync.Mutex
+	plans map[string]*MealPlan
+}
+
+func NewMemoryPlanStore() *MemoryPlanStore {
+	return &MemoryPlanStore{plans: make(map[string]*MealPlan)}
+}
+
+func (s *MemoryPlanStore) Get(id string) (*MealPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	plan, ok := s.plans[id]
+	if !ok {
+		return nil, &PlanNotFoundError{id}
+	}
+	return plan.copy(), nil
+}
+
+func (s *MemoryPlanStore) Put(plan *MealPlan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.plans[plan.ID] = plan.copy()
+	return nil
+}
+
+func (s *MemoryPlanStore) Update(id string, edit func(*MealPlan) error) (*MealPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	plan, ok := s.plans[id]
+	if !ok {
+		return nil, &PlanNotFoundError{id}
+	}
+	plan = plan.copy()
+	if err := edit(plan); err != nil {
+		return nil, err
+	}
+	s.plans[id] = plan.copy()
+	return plan, nil
+}
+
+// DiskPlanStore keeps meal plans as JSON files in a directory, one file
+// per plan.
+type DiskPlanStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewDiskPlanStore opens the plan store in dir, creating the directory if
+// needed.
+func NewDiskPlanStore(dir string) (*DiskPlanStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DiskPlanStore{dir: dir}, nil
+}
+
+func (s *DiskPlanStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func (s *DiskPlanStore) Get(id string) (*MealPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(id)
+}
+
+func (s *DiskPlanStore) get(id string) (*MealPlan, error) {
+	if !planIDRegex.MatchString(id) {
+		return nil, &PlanNotFoundError{id}
+	}
+	data, err := ioutil.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return nil, &PlanNotFoundError{id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var plan MealPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (s *DiskPlanStore) Put(plan *MealPlan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.put(plan)
+}
+
+func (s *DiskPlanStore) put(plan *MealPlan) error {
+	if !planIDRegex.MatchString(plan.ID) {
+		return &BadRequestError{"invalid plan id " + plan.ID}
+	}
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	tmp := s.path(plan.ID) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(plan.ID))
+}
+
+func (s *DiskPlanStore) Update(id string, edit func(*MealPlan) error) (*MealPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	plan, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := edit(plan); err != nil {
+		return nil, err
+	}
+	if err := s.put(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// planStoreFromEnv sets up Plans from CK_PLAN_DIR. Without it plans are
+// kept in the plans directory of Store, if there is one, so that they
+// survive a restart like the pages.
+func planStoreFromEnv() error {
+	dir := os.Getenv("CK_PLAN_DIR")
+	if dir == "" && Store != nil {
+		dir = filepath.Join(Store.dir, "plans")
+	}
+	if dir == "" {
+		return nil
+	}
+	store, err := NewDiskPlanStore(dir)
+	if err != nil {
+		return err
+	}
+	Plans = store
+	return nil
+}
@@ -0,0 +1,94 @@
+package ck
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testPlanStore(t *testing.T, store PlanStore) {
+	plan := &MealPlan{ID: "0123456789abcdef", Name: "Woche", Start: "2026-10-19", Days: 7,
+		Slots: []*MealSlot{{Day: 1, Meal: MealDinner, RecipeID: "1171381223217983"}}}
+	if _, err := store.Get(plan.ID); err == nil {
+		t.Fatal("Expected a missing plan to fail")
+	} else if _, ok := err.(*PlanNotFoundError); !ok {
+		t.Errorf("Expected a PlanNotFoundError, got: %v", err)
+	}
+	if err := store.Put(plan); err != nil {
+		t.Fatal(err)
+	}
+	plan.Slots[0].Servings = 8
+	got, err := store.Get(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Woche" || len(got.Slots) != 1 || got.Slots[0].Servings != 0 {
+		t.Errorf("Expected the stored plan to be unchanged, got: %+v", got)
+	}
+	updated, err := store.Update(plan.ID, func(p *MealPlan) error {
+		p.Slots[0].Servings = 4
+		return nil
+	})
+	if err != nil || updated.Slots[0].Servings != 4 {
+		t.Fatalf("Expected the update to be returned, got: %+v %v", updated, err)
+	}
+	failed := errors.New("failed")
+	if _, err := store.Update(plan.ID, func(p *MealPlan) error {
+		p.Name = "changed"
+		return failed
+	}); err != failed {
+		t.Errorf("Expected the edit error, got: %v", err)
+	}
+	got, _ = store.Get(plan.ID)
+	if got.Name != "Woche" || got.Slots[0].Servings != 4 {
+		t.Errorf("Expected only the successful update to be stored, got: %+v", got)
+	}
+	if _, err := store.Update("fedcba9876543210", func(p *MealPlan) error { return nil }); err == nil {
+		t.Error("Expected updating a missing plan to fail")
+	}
+}
+
+func TestMemoryPlanStore(t *testing.T) {
+	testPlanStore(t, NewMemoryPlanStore())
+}
+
+func TestDiskPlanStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ckplans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewDiskPlanStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testPlanStore(t, store)
+	if _, err := store.Get("../secret"); err == nil {
+		t.Error("Expected an invalid plan id to fail")
+	}
+	reopened, err := NewDiskPlanStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan, err := reopened.Get("0123456789abcdef"); err != nil || plan.Slots[0].Servings != 4 {
+		t.Errorf("Expected the plan to be kept on disk, got: %+v %v", plan, err)
+	}
+}
+
+func TestPlanStoreFromEnv(t *testing.T) {
+	defer usePlanStore()()
+	defer func(old string) { os.Setenv("CK_PLAN_DIR", old) }(os.Getenv("CK_PLAN_DIR"))
+	os.Unsetenv("CK_PLAN_DIR")
+	store, remove := seededStore(t)
+	defer remove()
+	defer func(old *DiskStore) { Store = old }(Store)
+	Store = store
+	if err := planStoreFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := Plans.(*DiskPlanStore); !ok || s.dir != filepath.Join(store.dir, "plans") {
+		t.Errorf("Expected plans to be kept next to the stored pages, got: %#v", Plans)
+	}
+}
@@ -0,0 +1,360 @@
+package ck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UserAgent is sent with every upstream request and used to pick the
+// matching group of robots.txt.
+var UserAgent = "ck/1.0 (+https://github.com/mswift42/ck)"
+
+// RateLimitedError is returned when too many requests are already waiting
+// for a host. RetryAfter estimates when a new request would be accepted.
+type RateLimitedError struct {
+	Host       string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("too many requests to %s, retry in %v", e.Host, e.RetryAfter)
+}
+
+// DisallowedError is returned for URLs excluded by robots.txt.
+type DisallowedError struct {
+	URL string
+}
+
+func (e *DisallowedError) Error() string {
+	return "disallowed by robots.txt: " + e.URL
+}
+
+// PoliteFetcher limits the requests Next makes to each host with a token
+// bucket and honors robots.txt. Requests wait for a token; once MaxQueue
+// requests are waiting for a host further ones fail with a
+// *RateLimitedError.
+type PoliteFetcher struct {
+	Next Fetcher
+	// Rate is the number of requests per second and host, Burst the
+	// number of requests allowed at once. A Crawl-delay in robots.txt
+	// lowers the rate.
+	Rate      float64
+	Burst     int
+	MaxQueue  int
+	RobotsTTL time.Duration
+	// RobotsRetry is how long a robots.txt that could not be fetched is
+	// treated as allowing everything before it is tried again.
+	RobotsRetry time.Duration
+
+	mu          sync.Mutex
+	hosts       map[string]*hostLimiter
+	robots      map[string]*robotsRules
+	robotsCalls map[string]*robotsCall
+	now         func() time.Time
+	sleep       func(context.Context, time.Duration) error
+}
+
+func NewPoliteFetcher(next Fetcher) *PoliteFetcher {
+	return &PoliteFetcher{Next: next, Rate: 2, Burst: 4, MaxQueue: 10,
+		RobotsTTL: 24 * time.Hour, RobotsRetry: 5 * time.Minute}
+}
+
+func (f *PoliteFetcher) Fetch(ctx context.Context, rawurl string) (*Page, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := f.robotsFor(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if !rules.allowed(path) {
+		return nil, &DisallowedError{rawurl}
+	}
+	if err := f.limiter(u.Host, rules).wait(ctx); err != nil {
+		return nil, err
+	}
+	return f.Next.Fetch(ctx, rawurl)
+}
+
+func (f *PoliteFetcher) clock() (func() time.Time, func(context.Context, time.Duration) error) {
+	now, sleep := f.now, f.sleep
+	if now == nil {
+		now = time.Now
+	}
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	return now, sleep
+}
+
+func (f *PoliteFetcher) limiter(host string, rules *robotsRules) *hostLimiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.hosts == nil {
+		f.hosts = make(map[string]*hostLimiter)
+	}
+	l, ok := f.hosts[host]
+	if !ok {
+		now, sleep := f.clock()
+		l = &hostLimiter{host: host, tokens: float64(f.Burst), now: now,
+			sleep: sleep, last: now()}
+		f.hosts[host] = l
+	}
+	l.mu.Lock()
+	l.rate, l.burst, l.maxQueue = f.Rate, float64(f.Burst), f.MaxQueue
+	if rules.crawlDelay > 0 {
+		l.rate = math.Min(l.rate, 1/rules.crawlDelay.Seconds())
+		l.burst = 1
+	}
+	l.mu.Unlock()
+	return l
+}
+
+// robotsTimeout bounds a robots.txt fetch, including the wait for a token.
+const robotsTimeout = 30 * time.Second
+
+// robotsCall is a robots.txt fetch in flight. rules and err are set once
+// done is closed.
+type robotsCall struct {
+	done  chan struct{}
+	rules *robotsRules
+	err   error
+}
+
+// robotsFor returns the cached robots.txt rules for the host of u,
+// fetching them when missing or older than RobotsTTL. Concurrent requests
+// for a host share one fetch, which waits for a token like any other
+// request and runs on its own context; each caller only waits as long as
+// its ctx allows. A robots.txt that cannot be fetched allows everything and is
+// retried after RobotsRetry.
+func (f *PoliteFetcher) robotsFor(ctx context.Context, u *url.URL) (*robotsRules, error) {
+	now, _ := f.clock()
+	f.mu.Lock()
+	rules, ok := f.robots[u.Host]
+	if ok && now().Sub(rules.fetched) < rules.ttl(f) {
+		f.mu.Unlock()
+		return rules, nil
+	}
+	call, pending := f.robotsCalls[u.Host]
+	if !pending {
+		call = &robotsCall{done: make(chan struct{})}
+		if f.robotsCalls == nil {
+			f.robotsCalls = make(map[string]*robotsCall)
+		}
+		f.robotsCalls[u.Host] = call
+	}
+	f.mu.Unlock()
+	if !pending {
+		if rules == nil {
+			rules = &robotsRules{}
+		}
+		// The fetch is shared, so it must not end with the request that
+		// happened to start it.
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), robotsTimeout)
+			defer cancel()
+			call.rules, call.err = f.fetchRobots(ctx, u, rules)
+			f.mu.Lock()
+			delete(f.robotsCalls, u.Host)
+			if call.err == nil {
+				if f.robots == nil {
+					f.robots = make(map[string]*robotsRules)
+				}
+				f.robots[u.Host] = call.rules
+			}
+			f.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	select {
+	case <-call.done:
+		return call.rules, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetchRobots fetches robots.txt for the host of u, taking a token from
+// the limiter set up with the previous rules. Only a failure to get a
+// token is returned as error.
+func (f *PoliteFetcher) fetchRobots(ctx context.Context, u *url.URL, previous *robotsRules) (*robotsRules, error) {
+	if err := f.limiter(u.Host, previous).wait(ctx); err != nil {
+		return nil, err
+	}
+	now, _ := f.clock()
+	page, err := f.Next.Fetch(ctx, u.Scheme+"://"+u.Host+"/robots.txt")
+	if err != nil || page.StatusCode >= 500 {
+		return &robotsRules{fetched: now(), failed: true}, nil
+	}
+	rules := &robotsRules{}
+	if page.StatusCode == http.StatusOK {
+		rules = parseRobots(page.Body, UserAgent)
+	}
+	rules.fetched = now()
+	return rules, nil
+}
+
+type hostLimiter struct {
+	mu       sync.Mutex
+	host     string
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	waiting  int
+	maxQueue int
+	now      func() time.Time
+	sleep    func(context.Context, time.Duration) error
+}
+
+// wait takes a token, sleeping until one is available or ctx is done.
+func (l *hostLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := l.now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		l.mu.Unlock()
+		return nil
+	}
+	delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	if l.waiting >= l.maxQueue {
+		l.mu.Unlock()
+		return &RateLimitedError{l.host, delay}
+	}
+	l.tokens--
+	l.waiting++
+	l.mu.Unlock()
+	err := l.sleep(ctx, delay)
+	l.mu.Lock()
+	l.waiting--
+	if err != nil {
+		// Hand the reserved token back.
+		l.tokens++
+	}
+	l.mu.Unlock()
+	return err
+}
+
+type robotsRules struct {
+	allow      []*robotsRule
+	disallow   []*robotsRule
+	crawlDelay time.Duration
+	fetched    time.Time
+	failed     bool
+}
+
+// ttl returns how long r stays valid in f.
+func (r *robotsRules) ttl(f *PoliteFetcher) time.Duration {
+	if r.failed {
+		return f.RobotsRetry
+	}
+	return f.RobotsTTL
+}
+
+// allowed reports whether path may be fetched. The longest matching rule
+// wins and Allow wins ties.
+func (r *robotsRules) allowed(path string) bool {
+	longest := func(rules []*robotsRule) int {
+		n := -1
+		for _, i := range rules {
+			if len(i.path) > n && i.match.MatchString(path) {
+				n = len(i.path)
+			}
+		}
+		return n
+	}
+	d := longest(r.disallow)
+	return d < 0 || longest(r.allow) >= d
+}
+
+// parseRobots reads the group of robots.txt that applies to agent, or the
+// "*" group if there is none for it.
+func parseRobots(body []byte, agent string) *robotsRules {
+	product := strings.ToLower(strings.SplitN(agent, "/", 2)[0])
+	groups := make(map[string]*robotsRules)
+	var current []*robotsRules
+	inRules := false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:colon]))
+		value := strings.TrimSpace(line[colon+1:])
+		switch key {
+		case "user-agent":
+			if inRules {
+				current, inRules = nil, false
+			}
+			name := strings.ToLower(value)
+			if groups[name] == nil {
+				groups[name] = &robotsRules{}
+			}
+			current = append(current, groups[name])
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			for _, g := range current {
+				if key == "allow" {
+					g.allow = append(g.allow, robotsPattern(value))
+				} else {
+					g.disallow = append(g.disallow, robotsPattern(value))
+				}
+			}
+		case "crawl-delay":
+			inRules = true
+			if d, err := strconv.ParseFloat(value, 64); err == nil {
+				for _, g := range current {
+					g.crawlDelay = time.Duration(d * float64(time.Second))
+				}
+			}
+		}
+	}
+	if g, ok := groups[product]; ok {
+		return g
+	}
+	if g, ok := groups["*"]; ok {
+		return g
+	}
+	return &robotsRules{}
+}
+
+type robotsRule struct {
+	path  string
+	match *regexp.Regexp
+}
+
+// robotsPattern turns a robots.txt path with "*" wildcards and an
+// optional "$" anchor into a rule.
+func robotsPattern(path string) *robotsRule {
+	expr := strings.TrimSuffix(path, "$")
+	expr = "^" + strings.Replace(regexp.QuoteMeta(expr), `\*`, ".*", -1)
+	if strings.HasSuffix(path, "$") {
+		expr += "$"
+	}
+	return &robotsRule{path, regexp.MustCompile(expr)}
+}
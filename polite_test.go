@@ -0,0 +1,183 @@
+package ck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testRobots = `# robots.txt
+User-agent: *
+Disallow: /user/
+Disallow: /rs/*/Rezepte.html$
+Allow: /user/profil/
+
+User-agent: ck
+User-agent: otherbot
+Disallow: /forum/
+Crawl-delay: 2
+`
+
+func TestParseRobots(t *testing.T) {
+	rules := parseRobots([]byte(testRobots), "Mozilla/5.0")
+	for _, i := range []struct {
+		path    string
+		allowed bool
+	}{
+		{"/rezepte/563451154612271/Gruene-Bohnen-im-Speckmantel.html", true},
+		{"/user/login", false},
+		{"/user/profil/abc/Spianata.html", true},
+		{"/rs/s0/bohnen/Rezepte.html", false},
+		{"/rs/s0/bohnen/Rezepte.html?x", true},
+	} {
+		if rules.allowed(i.path) != i.allowed {
+			t.Errorf("Expected %s to be allowed: %v", i.path, i.allowed)
+		}
+	}
+	rules = parseRobots([]byte(testRobots), UserAgent)
+	if rules.allowed("/forum/") || !rules.allowed("/user/login") {
+		t.Error("Expected the ck group to be used for the ck user agent")
+	}
+	if rules.crawlDelay != 2*time.Second {
+		t.Errorf("Expected crawl delay to be 2s, got: %v", rules.crawlDelay)
+	}
+}
+
+func TestPoliteFetcher(t *testing.T) {
+	var agents []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.UserAgent())
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /user/\n")
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+	now := time.Now()
+	var slept time.Duration
+	f := NewPoliteFetcher(NewHTTPFetcher(0))
+	f.Rate, f.Burst, f.MaxQueue = 1, 1, 1
+	f.now = func() time.Time { return now }
+	f.sleep = func(ctx context.Context, d time.Duration) error {
+		slept += d
+		now = now.Add(d)
+		return nil
+	}
+	if _, err := f.Fetch(context.Background(), ts.URL+"/user/login"); err == nil {
+		t.Error("Expected /user/login to be disallowed")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := f.Fetch(context.Background(), ts.URL+"/rezepte/1/a.html"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if slept != 2*time.Second {
+		t.Errorf("Expected both requests to wait behind robots.txt, got: %v", slept)
+	}
+	if len(agents) != 3 || agents[0] != UserAgent {
+		t.Errorf("Expected robots.txt to be fetched once with %q, got: %q",
+			UserAgent, agents)
+	}
+}
+
+func TestPoliteFetcherRobotsFailure(t *testing.T) {
+	var mu sync.Mutex
+	robots := 0
+	release := make(chan struct{})
+	next := fetcherFunc(func(ctx context.Context, url string) (*Page, error) {
+		if strings.HasSuffix(url, "/robots.txt") {
+			mu.Lock()
+			robots++
+			mu.Unlock()
+			<-release
+			return &Page{URL: url, StatusCode: 503}, nil
+		}
+		return &Page{URL: url, StatusCode: 200}, nil
+	})
+	now := time.Now()
+	f := NewPoliteFetcher(next)
+	f.now = func() time.Time { return now }
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.Fetch(context.Background(), "https://example.com/a"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	f.Fetch(context.Background(), "https://example.com/b")
+	if robots != 1 {
+		t.Errorf("Expected one robots.txt fetch for concurrent requests, got: %d", robots)
+	}
+	now = now.Add(f.RobotsRetry)
+	f.Fetch(context.Background(), "https://example.com/c")
+	if robots != 2 {
+		t.Errorf("Expected robots.txt to be retried after %v, got: %d fetches",
+			f.RobotsRetry, robots)
+	}
+}
+
+func TestPoliteFetcherRobotsCanceled(t *testing.T) {
+	release := make(chan struct{})
+	next := fetcherFunc(func(ctx context.Context, url string) (*Page, error) {
+		if strings.HasSuffix(url, "/robots.txt") {
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			return &Page{URL: url, StatusCode: 404}, nil
+		}
+		return &Page{URL: url, StatusCode: 200}, nil
+	})
+	f := NewPoliteFetcher(next)
+	first, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := f.Fetch(first, "https://example.com/a")
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		_, err := f.Fetch(context.Background(), "https://example.com/b")
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("Expected the first request to be canceled, got: %v", err)
+	}
+	close(release)
+	if err := <-errs; err != nil {
+		t.Errorf("Expected the second request to succeed, got: %v", err)
+	}
+}
+
+func TestRateLimitedHandler(t *testing.T) {
+	f := NewPoliteFetcher(&RecordingFetcher{})
+	f.robots = map[string]*robotsRules{"www.chefkoch.de": {fetched: time.Now()}}
+	f.MaxQueue, f.Burst = 0, 0
+	defer func(old Fetcher, cache Cache) {
+		DefaultFetcher, PageCache = old, cache
+	}(DefaultFetcher, PageCache)
+	DefaultFetcher, PageCache = f, NewLRUCache(10)
+	w := httptest.NewRecorder()
+	searchHandler(w, httptest.NewRequest("GET", "/search?query=bohnen", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got: %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After to be 1, got: %q", w.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,79 @@
+package ck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// prepInfo is the parsed "#preparation-info" paragraph of a detail page,
+// e.g. "Arbeitszeit: ca. 30 Min. / Koch-/Backzeit: ca. 15 Min. /
+// Schwierigkeitsgrad: simpel / Kalorien p. P.: keine Angabe".
+type prepInfo struct {
+	Worktime    string
+	Cookingtime string
+	Restingtime string
+	Difficulty  string
+	Calories    string
+	Unknown     map[string]string
+	Warnings    []string
+}
+
+// prepInfoKeys maps the labels chefkoch uses to the prepInfo fields.
+var prepInfoKeys = map[string]func(pi *prepInfo) *string{
+	"Arbeitszeit":        func(pi *prepInfo) *string { return &pi.Worktime },
+	"Koch-/Backzeit":     func(pi *prepInfo) *string { return &pi.Cookingtime },
+	"Kochzeit":           func(pi *prepInfo) *string { return &pi.Cookingtime },
+	"Backzeit":           func(pi *prepInfo) *string { return &pi.Cookingtime },
+	"Ruhezeit":           func(pi *prepInfo) *string { return &pi.Restingtime },
+	"Schwierigkeitsgrad": func(pi *prepInfo) *string { return &pi.Difficulty },
+	"Kalorien p. P.":     func(pi *prepInfo) *string { return &pi.Calories },
+	"Kalorien":           func(pi *prepInfo) *string { return &pi.Calories },
+}
+
+func (rdd *RecipeDetailDocument) prepinfo() *prepInfo {
+	sel := rdd.doc.Find("#preparation-info")
+	if sel.Length() == 0 {
+		return &prepInfo{Warnings: []string{"no preparation info found"}}
+	}
+	return parsePrepInfo(sel.Text())
+}
+
+// parsePrepInfo splits the preparation info into "label: value" sections
+// separated by "/". Sections it cannot make sense of are reported in
+// Warnings and labels it does not know end up in Unknown.
+func parsePrepInfo(text string) *prepInfo {
+	pi := &prepInfo{}
+	text = strings.Join(strings.Fields(text), " ")
+	// Keep the slash in "Koch-/Backzeit" from splitting the section.
+	text = strings.Replace(text, "Koch-/Backzeit", "Koch-Backzeit", -1)
+	for _, section := range strings.Split(text, "/") {
+		section = strings.TrimSpace(section)
+		if section == "" {
+			continue
+		}
+		key, value := section, ""
+		if colon := strings.Index(section, ":"); colon >= 0 {
+			key = strings.TrimSpace(section[:colon])
+			value = strings.TrimSpace(section[colon+1:])
+		}
+		if key == "Koch-Backzeit" {
+			key = "Koch-/Backzeit"
+		}
+		if value == "" {
+			pi.Warnings = append(pi.Warnings,
+				fmt.Sprintf("preparation info %q has no value", key))
+		}
+		if value == "keine Angabe" {
+			value = "NA"
+		}
+		if field, ok := prepInfoKeys[key]; ok {
+			*field(pi) = value
+			continue
+		}
+		if pi.Unknown == nil {
+			pi.Unknown = make(map[string]string)
+		}
+		pi.Unknown[key] = value
+	}
+	return pi
+}
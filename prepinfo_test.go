@@ -0,0 +1,64 @@
+package ck
+
+import (
+	"reflect"
+	"testing"
+)
+
+var prepInfos = []struct {
+	text string
+	want *prepInfo
+}{
+	{
+		`Arbeitszeit:
+                ca. 30 Min.
+                    / Koch-/Backzeit:
+                    ca. 15 Min.
+                / Schwierigkeitsgrad:
+                simpel
+                / Kalorien p. P.:
+                    keine Angabe`,
+		&prepInfo{Worktime: "ca. 30 Min.", Cookingtime: "ca. 15 Min.",
+			Difficulty: "simpel", Calories: "NA"},
+	},
+	{
+		"Arbeitszeit: ca. 20 Min. / Ruhezeit: ca. 1 Std. / Schwierigkeitsgrad: normal / Kalorien p. P.: ca. 350",
+		&prepInfo{Worktime: "ca. 20 Min.", Restingtime: "ca. 1 Std.",
+			Difficulty: "normal", Calories: "ca. 350"},
+	},
+	{
+		"Ruhezeit",
+		&prepInfo{Warnings: []string{`preparation info "Ruhezeit" has no value`}},
+	},
+	{
+		"Arbeitszeit: ca. 10 Min. / Backzeit: 45 Min. / Portionsgröße: klein",
+		&prepInfo{Worktime: "ca. 10 Min.", Cookingtime: "45 Min.",
+			Unknown: map[string]string{"Portionsgröße": "klein"}},
+	},
+	{
+		"",
+		&prepInfo{},
+	},
+}
+
+func TestParsePrepInfo(t *testing.T) {
+	for _, i := range prepInfos {
+		pi := parsePrepInfo(i.text)
+		if !reflect.DeepEqual(pi, i.want) {
+			t.Errorf("Expected prepinfo of %q to be %+v, got: %+v", i.text, i.want, pi)
+		}
+	}
+}
+
+func TestMissingPrepInfo(t *testing.T) {
+	doc := detailDocument("testhtml/schupfnudel.html")
+	doc.Find("#preparation-info").Remove()
+	rd := (&RecipeDetailDocument{doc}).newRecipeDetail()
+	if len(rd.Warnings) != 1 {
+		t.Errorf("Expected one warning, got: %q", rd.Warnings)
+	}
+	if rd.PreptimeMinutes != 30 {
+		t.Errorf("Expected preptime from JSON-LD to be 30, got: %d",
+			rd.PreptimeMinutes)
+	}
+}
@@ -0,0 +1,76 @@
+package ck
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	recipeIDRegex   = regexp.MustCompile(`^\d+$`)
+	recipePathRegex = regexp.MustCompile(`^/rezepte/(\d+)(/[^/]*)?$`)
+)
+
+// recipeURL validates the recipeurl parameter of /recipedetail and
+// returns the chefkoch URL to fetch. Only https recipe pages on
+// chefkoch.de, e.g. https://www.chefkoch.de/rezepte/<id>/<name>.html,
+// and bare recipe IDs are accepted, so the service cannot be made to
+// fetch arbitrary URLs.
+func recipeURL(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", &BadRequestError{"recipeurl is required"}
+	}
+	if recipeIDRegex.MatchString(s) {
+		return recipeIDURL(s), nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", &BadRequestError{"recipeurl is not a valid URL"}
+	}
+	if u.Scheme != "https" {
+		return "", &BadRequestError{"recipeurl must be an https URL"}
+	}
+	if u.User != nil || u.Port() != "" ||
+		(u.Host != "www.chefkoch.de" && u.Host != "chefkoch.de") {
+		return "", &BadRequestError{"recipeurl must point to chefkoch.de"}
+	}
+	if !recipePathRegex.MatchString(u.Path) {
+		return "", &BadRequestError{
+			"recipeurl must be a recipe page like https://www.chefkoch.de/rezepte/<id>/<name>.html"}
+	}
+	return "https://www.chefkoch.de" + u.Path, nil
+}
+
+// recipeID returns the numeric chefkoch ID of a recipe URL, or "" if u
+// is not a recipe URL.
+func recipeID(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	m := recipePathRegex.FindStringSubmatch(parsed.Path)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// recipeIDURL is the chefkoch URL of the recipe with the given ID.
+func recipeIDURL(id string) string {
+	return "https://www.chefkoch.de/rezepte/" + id + "/"
+}
+
+// sameHostRedirect is an http.Client CheckRedirect func that stops
+// redirects to hosts other than the one originally requested.
+func sameHostRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if req.URL.Host != via[0].URL.Host {
+		return errors.New("redirect to other host " + req.URL.Host + " blocked")
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package ck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecipeURL(t *testing.T) {
+	tests := []struct {
+		input string
+		url   string
+		ok    bool
+	}{
+		{"1171381223217983", "https://www.chefkoch.de/rezepte/1171381223217983/", true},
+		{schupfnudelURL, schupfnudelURL, true},
+		{"https://chefkoch.de/rezepte/1171381223217983/Schupfnudel-Bohnen-Pfanne.html?utm=x",
+			schupfnudelURL, true},
+		{"https://www.chefkoch.de/rezepte/1171381223217983/", "https://www.chefkoch.de/rezepte/1171381223217983/", true},
+		{"", "", false},
+		{"http://www.chefkoch.de/rezepte/1171381223217983/x.html", "", false},
+		{"https://www.chefkoch.de.evil.com/rezepte/1/x.html", "", false},
+		{"https://www.chefkoch.de:8080/rezepte/1/x.html", "", false},
+		{"https://user@www.chefkoch.de/rezepte/1/x.html", "", false},
+		{"https://www.chefkoch.de/user/login", "", false},
+		{"https://www.chefkoch.de/rezepte/1/../../user/x.html", "", false},
+		{"http://169.254.169.254/latest/meta-data/", "", false},
+		{"file:///etc/passwd", "", false},
+		{"12a", "", false},
+	}
+	for _, i := range tests {
+		url, err := recipeURL(i.input)
+		if (err == nil) != i.ok || url != i.url {
+			t.Errorf("Expected %q to give %q (ok %v), got: %q, %v",
+				i.input, i.url, i.ok, url, err)
+		}
+		if err != nil {
+			if _, ok := err.(*BadRequestError); !ok {
+				t.Errorf("Expected a *BadRequestError, got: %T", err)
+			}
+		}
+	}
+}
+
+func TestRedirectBlocked(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secret")
+	}))
+	defer other.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/away" {
+			http.Redirect(w, r, other.URL, http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/here" {
+			http.Redirect(w, r, "/ok", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+	f := NewHTTPFetcher(0)
+	if _, err := f.Fetch(context.Background(), ts.URL+"/away"); err == nil {
+		t.Error("Expected a redirect to another host to fail")
+	}
+	page, err := f.Fetch(context.Background(), ts.URL+"/here")
+	if err != nil || string(page.Body) != "ok" {
+		t.Errorf("Expected a redirect on the same host to be followed, got: %v", err)
+	}
+}
+
+func TestRecipeID(t *testing.T) {
+	tests := []struct {
+		url string
+		id  string
+	}{
+		{schupfnudelURL, "1171381223217983"},
+		{"https://www.chefkoch.de/rezepte/563451154612271/", "563451154612271"},
+		{"https://www.chefkoch.de/rs/s0/bohnen/Rezepte.html", ""},
+		{"", ""},
+	}
+	for _, i := range tests {
+		if id := recipeID(i.url); id != i.id {
+			t.Errorf("Expected id of %q to be %q, got: %q", i.url, i.id, id)
+		}
+	}
+	doc := detailDocument("testhtml/bohnen.html")
+	for _, i := range allRecipes(doc) {
+		if i.ID == "" || !strings.Contains(i.Url, "/rezepte/"+i.ID+"/") {
+			t.Errorf("Expected id to be taken from %q, got: %q", i.Url, i.ID)
+		}
+	}
+	doc = detailDocument("testhtml/schupfnudel.html")
+	rd := (&RecipeDetailDocument{doc}).newRecipeDetail()
+	if rd.ID != "1171381223217983" {
+		t.Errorf("Expected detail id to be 1171381223217983, got: %q", rd.ID)
+	}
+}
+
+func TestRecipeIDWithoutCanonical(t *testing.T) {
+	defer useSeededStore(t)()
+	doc := detailDocument("testhtml/schupfnudel.html")
+	doc.Find(`link[rel="canonical"]`).Remove()
+	html, err := doc.Html()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recurl := "https://www.chefkoch.de/rezepte/42/Ohne-Canonical.html"
+	if err := Store.Put(recurl, []byte(html), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	rd, err := fetchRecipeDetail(context.Background(), recurl)
+	if err != nil {
+		t.Fatal("Expected error to be nil, got: ", err)
+	}
+	if rd.ID != "42" {
+		t.Errorf("Expected id to be taken from the requested url, got: %q", rd.ID)
+	}
+}
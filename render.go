@@ -0,0 +1,279 @@
+package ck
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// recipeFact is a labelled property of a recipe shown in the header of
+// the text and Markdown renderings.
+type recipeFact struct {
+	label, value string
+}
+
+func recipeFacts(rd *RecipeDetail) []recipeFact {
+	var facts []recipeFact
+	add := func(label, value string) {
+		value = normalizeSpace(value)
+		if value != "" && value != "NA" {
+			facts = append(facts, recipeFact{label, value})
+		}
+	}
+	add("Rating", rd.Rating)
+	add("Difficulty", rd.Difficulty)
+	add("Preparation", rd.Preptime)
+	add("Cooking", rd.Cookingtime)
+	add("Resting", rd.Restingtime)
+	if rd.Servings > 0 {
+		add("Servings", strconv.Itoa(rd.Servings))
+	}
+	add("Calories", rd.Calories)
+	add("Author", rd.Author)
+	if rd.ID != "" {
+		add("Source", recipeIDURL(rd.ID))
+	}
+	return facts
+}
+
+// recipeSteps returns the method of rd as a list of steps.
+func recipeSteps(rd *RecipeDetail) []string {
+	var steps []string
+	for _, i := range rd.Steps {
+		steps = append(steps, i.Text)
+	}
+	if len(steps) == 0 && strings.TrimSpace(rd.Method) != "" {
+		steps = append(steps, strings.TrimSpace(rd.Method))
+	}
+	return steps
+}
+
+// WriteText writes rd as plain text, e.g. for a terminal.
+func WriteText(w io.Writer, rd *RecipeDetail) error {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, rd.Title)
+	fmt.Fprintln(&buf, strings.Repeat("=", len([]rune(rd.Title))))
+	fmt.Fprintln(&buf)
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	for _, i := range recipeFacts(rd) {
+		fmt.Fprintf(tw, "%s:\t%s\n", i.label, i.value)
+	}
+	tw.Flush()
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "Ingredients")
+	tw = tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	for _, i := range rd.Ingredients {
+		fmt.Fprintf(tw, "  %s\t%s\n", normalizeSpace(i.Amount), normalizeSpace(i.Ingredient))
+	}
+	tw.Flush()
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "Method")
+	for n, i := range recipeSteps(rd) {
+		fmt.Fprintf(&buf, "%d. %s\n", n+1, i)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`,
+	"`", "\\`", "[", `\[`, "]", `\]`, "<", "&lt;")
+
+// WriteMarkdown writes rd as a Markdown document.
+func WriteMarkdown(w io.Writer, rd *RecipeDetail) error {
+	md := markdownEscaper.Replace
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n\n", md(rd.Title))
+	if rd.Image != "" {
+		fmt.Fprintf(&buf, "![%s](%s)\n\n", md(rd.Title), rd.Image)
+	}
+	for _, i := range recipeFacts(rd) {
+		fmt.Fprintf(&buf, "- **%s:** %s\n", i.label, md(i.value))
+	}
+	fmt.Fprint(&buf, "\n## Ingredients\n\n")
+	for _, i := range rd.Ingredients {
+		line := strings.TrimSpace(normalizeSpace(i.Amount) + " " + normalizeSpace(i.Ingredient))
+		fmt.Fprintf(&buf, "- %s\n", md(line))
+	}
+	fmt.Fprint(&buf, "\n## Method\n\n")
+	for n, i := range recipeSteps(rd) {
+		fmt.Fprintf(&buf, "%d. %s\n", n+1, md(i))
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+var htmlTemplate = template.Must(template.New("recipe").Parse(`<!DOCTYPE html>
+<html lang="de">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Georgia, serif; max-width: 42em; margin: 2em auto; padding: 0 1em; color: #222; line-height: 1.5; }
+h1 { margin-bottom: 0.25em; }
+img { max-width: 100%; height: auto; }
+dl.facts { display: grid; grid-template-columns: max-content auto; gap: 0.1em 1em; }
+dl.facts dt { font-weight: bold; }
+dl.facts dd { margin: 0; }
+table.ingredients { border-collapse: collapse; }
+table.ingredients td { padding: 0.2em 0.8em 0.2em 0; border-bottom: 1px solid #ddd; vertical-align: top; }
+table.ingredients td.amount { text-align: right; white-space: nowrap; }
+ol.steps li { margin-bottom: 0.6em; }
+footer { margin-top: 2em; font-size: 0.85em; color: #555; }
+@media print {
+  body { margin: 0; max-width: none; font-size: 11pt; }
+  img { max-height: 6cm; }
+  a { color: inherit; text-decoration: none; }
+  h2, table.ingredients tr, ol.steps li { break-inside: avoid; }
+}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Image}}<img src="{{.Image}}" alt="{{.Title}}">
+{{end}}<dl class="facts">
+{{range .Facts}}<dt>{{.Label}}</dt><dd>{{.Value}}</dd>
+{{end}}</dl>
+<h2>Ingredients</h2>
+<table class="ingredients">
+{{range .Ingredients}}<tr><td class="amount">{{.Amount}}</td><td>{{.Ingredient}}</td></tr>
+{{end}}</table>
+<h2>Method</h2>
+<ol class="steps">
+{{range .Steps}}<li>{{.}}</li>
+{{end}}</ol>
+<footer>{{if .Author}}Recipe by {{.Author}} on {{else}}Recipe from {{end}}{{if .Source}}<a href="{{.Source}}">{{.Source}}</a>{{else}}chefkoch.de{{end}}</footer>
+</body>
+</html>
+`))
+
+// WriteHTML writes rd as a self-contained HTML page laid out for
+// printing.
+func WriteHTML(w io.Writer, rd *RecipeDetail) error {
+	type fact struct{ Label, Value string }
+	type ingredient struct{ Amount, Ingredient string }
+	data := struct {
+		Title, Image, Author, Source string
+		Facts                        []fact
+		Ingredients                  []ingredient
+		Steps                        []string
+	}{Title: rd.Title, Image: rd.Image, Author: rd.Author, Steps: recipeSteps(rd)}
+	if rd.ID != "" {
+		data.Source = recipeIDURL(rd.ID)
+	}
+	for _, i := range recipeFacts(rd) {
+		if i.label != "Author" && i.label != "Source" {
+			data.Facts = append(data.Facts, fact{i.label, i.value})
+		}
+	}
+	for _, i := range rd.Ingredients {
+		data.Ingredients = append(data.Ingredients,
+			ingredient{normalizeSpace(i.Amount), normalizeSpace(i.Ingredient)})
+	}
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeJSONDetail(w io.Writer, rd *RecipeDetail) error {
+	json, err := recipeDetailToJson(rd)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(json)
+	return err
+}
+
+// recipeFormat is an output format of /recipedetail.
+type recipeFormat struct {
+	names       []string
+	contentType string
+	write       func(io.Writer, *RecipeDetail) error
+	// extension is set for formats that are served as a download.
+	extension string
+}
+
+// recipeFormats lists the output formats in order of preference for
+// Accept headers with wildcards.
+var recipeFormats = []*recipeFormat{
+	{[]string{"json"}, "application/json; charset=utf-8", writeJSONDetail, ""},
+	{[]string{"html"}, "text/html; charset=utf-8", WriteHTML, ""},
+	{[]string{"md", "markdown"}, "text/markdown; charset=utf-8", WriteMarkdown, ""},
+	{[]string{"txt", "text"}, "text/plain; charset=utf-8", WriteText, ""},
+	{[]string{"cooklang", "cook"}, "text/x-cooklang; charset=utf-8", WriteCooklang, ""},
+	{[]string{"jsonld"}, "application/ld+json; charset=utf-8", WriteJSONLD, ""},
+	{[]string{"mealmaster", "mmf"}, "text/x-mealmaster; charset=utf-8", WriteMealMaster, ".mmf"},
+	{[]string{"paprika"}, "application/zip", WritePaprika, ".paprikarecipes"},
+}
+
+// mediaType strips the parameters from contentType.
+func mediaType(contentType string) string {
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		return contentType[:i]
+	}
+	return contentType
+}
+
+// requestRecipeFormat returns the format named by the format parameter
+// of r or, without one, the format preferred by its Accept header.
+// JSON is the default.
+func requestRecipeFormat(r *http.Request) (*recipeFormat, error) {
+	if name := r.FormValue("format"); name != "" {
+		for _, f := range recipeFormats {
+			for _, i := range f.names {
+				if strings.EqualFold(name, i) {
+					return f, nil
+				}
+			}
+		}
+		return nil, &BadRequestError{"format must be json, html, md, txt, cooklang, jsonld, mealmaster or paprika"}
+	}
+	types := make([]string, len(recipeFormats))
+	for n, f := range recipeFormats {
+		types[n] = mediaType(f.contentType)
+	}
+	if n := acceptedMediaType(r.Header.Get("Accept"), types); n >= 0 {
+		return recipeFormats[n], nil
+	}
+	return recipeFormats[0], nil
+}
+
+// acceptedMediaType returns the index of the media type in types with
+// the highest quality in the Accept header accept, or -1 if it accepts
+// none of them.
+func acceptedMediaType(accept string, types []string) int {
+	best := -1
+	bestQ := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+		for n, t := range types {
+			if mt == t || mt == "*/*" ||
+				(strings.HasSuffix(mt, "/*") && strings.HasPrefix(t, mt[:len(mt)-1])) {
+				best, bestQ = n, q
+				break
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,170 @@
+package ck
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	doc := detailDocument("testhtml/schupfnudel.html")
+	rd := (&RecipeDetailDocument{doc}).newRecipeDetail()
+	var buf bytes.Buffer
+	if err := WriteText(&buf, rd); err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []string{
+		"Schupfnudel - Bohnen - Pfanne\n=============================\n",
+		"Servings:     2\n",
+		"  500 g        Schupfnudeln (Kühlregal)\n",
+		"               Olivenöl\n",
+		"\nMethod\n1. Die Prinzessböhnchen für ca. 5 Min. in kochendem Wasser garen.\n2. ",
+	} {
+		if !strings.Contains(buf.String(), i) {
+			t.Errorf("Expected text to contain %q, got:\n%s", i, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "Calories") {
+		t.Error("Expected unknown calories to be left out")
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	doc := detailDocument("testhtml/gruene_bohnen_mit_speck.html")
+	rd := (&RecipeDetailDocument{doc}).newRecipeDetail()
+	rd.Title = "Grüne Bohnen *mit* Speck"
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, rd); err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []string{
+		"# Grüne Bohnen \\*mit\\* Speck\n\n![",
+		"- **Difficulty:** normal\n",
+		"- **Source:** https://www.chefkoch.de/rezepte/2406611380140966/\n",
+		"\n## Ingredients\n\n- 500 g Bohnen, grüne, frisch oder TK\n",
+		"- Pfeffer\n",
+		"\n## Method\n\n1. Grüne Bohnen putzen",
+	} {
+		if !strings.Contains(buf.String(), i) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", i, buf.String())
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	doc := detailDocument("testhtml/schupfnudel.html")
+	rd := (&RecipeDetailDocument{doc}).newRecipeDetail()
+	rd.Ingredients[0].Ingredient = "Schupfnudeln <Kühlregal>"
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, rd); err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []string{
+		"<title>Schupfnudel - Bohnen - Pfanne</title>",
+		"@media print",
+		`<tr><td class="amount">500 g</td><td>Schupfnudeln &lt;Kühlregal&gt;</td></tr>`,
+		"<dt>Preparation</dt><dd>ca. 30 Min.</dd>",
+		"<li>Die Prinzessböhnchen für ca. 5 Min. in kochendem Wasser garen.</li>",
+		`Recipe by miaka-li on <a href="https://www.chefkoch.de/rezepte/1171381223217983/">`,
+	} {
+		if !strings.Contains(buf.String(), i) {
+			t.Errorf("Expected HTML to contain %q", i)
+		}
+	}
+	if strings.Contains(buf.String(), "<link") || strings.Contains(buf.String(), "<script") {
+		t.Error("Expected a self-contained page")
+	}
+}
+
+func TestRequestRecipeFormat(t *testing.T) {
+	tests := []struct {
+		query  string
+		accept string
+		format string
+	}{
+		{"", "", "json"},
+		{"format=md", "", "md"},
+		{"format=Markdown", "text/html", "md"},
+		{"format=txt", "", "txt"},
+		{"format=html", "", "html"},
+		{"", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "html"},
+		{"", "text/markdown", "md"},
+		{"", "text/plain;q=0.5, text/markdown;q=0.4", "txt"},
+		{"", "text/*", "html"},
+		{"", "*/*", "json"},
+		{"", "image/png", "json"},
+		{"", "application/json;q=0, text/plain", "txt"},
+		{"format=cooklang", "", "cooklang"},
+		{"", "text/x-cooklang", "cooklang"},
+		{"format=mmf", "", "mealmaster"},
+		{"", "application/ld+json", "jsonld"},
+		{"", "application/zip", "paprika"},
+		{"format=pdf", "", ""},
+	}
+	for _, i := range tests {
+		r := httptest.NewRequest("GET", "/recipedetail?"+i.query, nil)
+		if i.accept != "" {
+			r.Header.Set("Accept", i.accept)
+		}
+		f, err := requestRecipeFormat(r)
+		if i.format == "" {
+			if err == nil {
+				t.Errorf("Expected %q to be rejected", i.query)
+			}
+			continue
+		}
+		if err != nil || f.names[0] != i.format {
+			t.Errorf("Expected format %s for %q and %q, got: %v", i.format, i.query, i.accept, err)
+		}
+	}
+}
+
+func TestDetailHandlerFormats(t *testing.T) {
+	defer useSeededStore(t)()
+	tests := []struct {
+		query       string
+		contentType string
+		prefix      string
+	}{
+		{"format=md", "text/markdown; charset=utf-8", "# Schupfnudel"},
+		{"format=txt&servings=4", "text/plain; charset=utf-8", "Schupfnudel - Bohnen - Pfanne\n"},
+		{"format=html", "text/html; charset=utf-8", "<!DOCTYPE html>"},
+		{"", "application/json; charset=utf-8", `{"id":"1171381223217983"`},
+		{"format=jsonld", "application/ld+json; charset=utf-8", "{\n  \"@context\""},
+		{"format=mealmaster", "text/x-mealmaster; charset=utf-8", "MMMMM----- "},
+		{"format=paprika", "application/zip", "PK"},
+	}
+	for _, i := range tests {
+		w := httptest.NewRecorder()
+		detailHandler(w, httptest.NewRequest("GET",
+			"/recipedetail?recipeurl="+schupfnudelURL+"&"+i.query, nil))
+		if w.Code != 200 || w.Header().Get("Content-Type") != i.contentType {
+			t.Errorf("Expected 200 with %s for %q, got: %d with %s", i.contentType,
+				i.query, w.Code, w.Header().Get("Content-Type"))
+		}
+		if !strings.HasPrefix(w.Body.String(), i.prefix) {
+			t.Errorf("Expected the %q response to start with %q", i.query, i.prefix)
+		}
+	}
+}
+
+func TestDetailHandlerAttachment(t *testing.T) {
+	defer useSeededStore(t)()
+	tests := []struct {
+		query       string
+		disposition string
+	}{
+		{"format=paprika", `attachment; filename="1171381223217983.paprikarecipes"`},
+		{"format=mmf", `attachment; filename="1171381223217983.mmf"`},
+		{"format=md", ""},
+	}
+	for _, i := range tests {
+		w := httptest.NewRecorder()
+		detailHandler(w, httptest.NewRequest("GET",
+			"/recipedetail?recipeurl="+schupfnudelURL+"&"+i.query, nil))
+		if got := w.Header().Get("Content-Disposition"); got != i.disposition {
+			t.Errorf("Expected Content-Disposition %q for %q, got: %q", i.disposition, i.query, got)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package ck
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// UpstreamTimeout bounds the upstream work of a single handler request,
+// including retries.
+var UpstreamTimeout = 30 * time.Second
+
+// RetryFetcher retries transient failures of Next, that is timeouts,
+// connection resets and 502, 503 and 504 responses, with jittered
+// exponential backoff. Retrying stops after MaxAttempts attempts, when
+// the next delay would exceed Budget or when the context is done.
+type RetryFetcher struct {
+	Next        Fetcher
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Budget      time.Duration
+
+	sleep func(context.Context, time.Duration) error
+}
+
+func NewRetryFetcher(next Fetcher) *RetryFetcher {
+	return &RetryFetcher{Next: next, MaxAttempts: 4, BaseDelay: 250 * time.Millisecond,
+		MaxDelay: 4 * time.Second, Budget: 20 * time.Second}
+}
+
+func (f *RetryFetcher) Fetch(ctx context.Context, url string) (*Page, error) {
+	sleep := f.sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		page, err := f.Next.Fetch(ctx, url)
+		if !retryable(page, err) || attempt >= f.MaxAttempts || ctx.Err() != nil {
+			return page, err
+		}
+		delay := f.backoff(attempt)
+		if time.Since(start)+delay > f.Budget {
+			return page, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return page, err
+		}
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = http.StatusText(page.StatusCode)
+		}
+		log.Printf("fetching %s: attempt %d failed (%s), retrying in %v",
+			url, attempt, reason, delay)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns a random delay of up to BaseDelay doubled for every
+// attempt so far, capped at MaxDelay.
+func (f *RetryFetcher) backoff(attempt int) time.Duration {
+	max := f.BaseDelay << uint(attempt-1)
+	if max > f.MaxDelay || max <= 0 {
+		max = f.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(max)) + 1)
+}
+
+func retryable(page *Page, err error) bool {
+	if err == nil {
+		switch page.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	// The http client wraps these in *url.Error.
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
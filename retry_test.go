@@ -0,0 +1,126 @@
+package ck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryFetcher(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/down" || requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+	var delays []time.Duration
+	f := NewRetryFetcher(NewHTTPFetcher(0))
+	f.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	page, err := f.Fetch(context.Background(), ts.URL+"/up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.StatusCode != 200 || requests != 3 {
+		t.Errorf("Expected 200 after 3 requests, got: %d after %d",
+			page.StatusCode, requests)
+	}
+	for i, d := range delays {
+		if max := f.BaseDelay << uint(i); d <= 0 || d > max {
+			t.Errorf("Expected delay %d to be in (0, %v], got: %v", i, max, d)
+		}
+	}
+
+	requests = 0
+	page, err = f.Fetch(context.Background(), ts.URL+"/down")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.StatusCode != 503 || requests != f.MaxAttempts {
+		t.Errorf("Expected 503 after %d requests, got: %d after %d",
+			f.MaxAttempts, page.StatusCode, requests)
+	}
+
+	requests = 0
+	f.Budget = 0
+	f.Fetch(context.Background(), ts.URL+"/down")
+	if requests != 1 {
+		t.Errorf("Expected no retries without a budget, got: %d requests", requests)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	wrap := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://www.chefkoch.de/", Err: err}
+	}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{wrap(io.ErrUnexpectedEOF), true},
+		{wrap(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{wrap(errors.New("connection reset by peer, but only in the text")), false},
+		{wrap(errors.New("no such host")), false},
+	}
+	for _, i := range tests {
+		if got := retryable(nil, i.err); got != i.want {
+			t.Errorf("Expected %v to be retryable: %v", i.err, i.want)
+		}
+	}
+}
+
+func TestRetryFetcherContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+	f := NewRetryFetcher(NewHTTPFetcher(0))
+	f.BaseDelay, f.MaxDelay = time.Hour, time.Hour
+	f.Budget = 2 * time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	if _, err := f.Fetch(ctx, ts.URL); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected the backoff to stop when the context is cancelled")
+	}
+}
+
+func TestTimeoutStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{upstreamError("https://www.chefkoch.de/", context.DeadlineExceeded), 504},
+		{upstreamError("https://www.chefkoch.de/",
+			fmt.Errorf("reading body: %w", context.DeadlineExceeded)), 504},
+		{upstreamError("https://www.chefkoch.de/", errors.New("connection refused")), 502},
+		{fmt.Errorf("search: %w", &UpstreamNotFoundError{URL: "https://www.chefkoch.de/"}), 404},
+		{fmt.Errorf("plan: %w", &BadRequestError{Message: "bad"}), 400},
+	}
+	for _, i := range tests {
+		if s, _ := errorStatus(i.err); s != i.status {
+			t.Errorf("Expected %d for %v, got: %d", i.status, i.err, s)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package ck
+
+import (
+	"net/http"
+	"strings"
+)
+
+// v1SearchHandler serves GET /v1/search?q=, taking the same page, cursor
+// and filter parameters as /search.
+func v1SearchHandler(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.FormValue("q"))
+	if q == "" {
+		writeError(w, r, &BadRequestError{"q is required"})
+		return
+	}
+	serveSearch(w, r, q)
+}
+
+// v1RecipeHandler serves GET /v1/recipes/{id}, taking the same
+// parameters as /recipedetail.
+func v1RecipeHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/recipes/"), "/")
+	if !recipeIDRegex.MatchString(id) {
+		writeError(w, r, &BadRequestError{"recipe id must be numeric, e.g. /v1/recipes/563451154612271"})
+		return
+	}
+	serveRecipeDetail(w, r, recipeIDURL(id))
+}
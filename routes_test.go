@@ -0,0 +1,72 @@
+package ck
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestV1Routes(t *testing.T) {
+	detail, err := ioutil.ReadFile("testhtml/schupfnudel.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	search, err := ioutil.ReadFile("testhtml/bohnen.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(old Fetcher, cache Cache) {
+		DefaultFetcher, PageCache = old, cache
+	}(DefaultFetcher, PageCache)
+	var fetched []string
+	DefaultFetcher = fetcherFunc(func(ctx context.Context, url string) (*Page, error) {
+		fetched = append(fetched, url)
+		if url == queryUrl("bohnen", "0") {
+			return &Page{URL: url, StatusCode: 200, Body: search}, nil
+		}
+		return &Page{URL: url, StatusCode: 200, Body: detail}, nil
+	})
+	PageCache = NewLRUCache(10)
+
+	w := httptest.NewRecorder()
+	v1RecipeHandler(w, httptest.NewRequest("GET", "/v1/recipes/1171381223217983?servings=4", nil))
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	var rd RecipeDetail
+	if err := json.Unmarshal(w.Body.Bytes(), &rd); err != nil {
+		t.Fatal(err)
+	}
+	if rd.ID != "1171381223217983" || rd.Servings != 4 {
+		t.Errorf("Expected recipe 1171381223217983 for 4, got: %q for %d", rd.ID, rd.Servings)
+	}
+	if fetched[0] != "https://www.chefkoch.de/rezepte/1171381223217983/" {
+		t.Errorf("Expected the recipe to be fetched by id, got: %q", fetched[0])
+	}
+
+	w = httptest.NewRecorder()
+	v1SearchHandler(w, httptest.NewRequest("GET", "/v1/search?q=bohnen", nil))
+	var resp SearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Query != "bohnen" || len(resp.Results) != 30 || resp.Results[0].ID == "" {
+		t.Errorf("Expected 30 results with ids for bohnen, got: %d for %q",
+			len(resp.Results), resp.Query)
+	}
+
+	for _, path := range []string{"/v1/recipes/", "/v1/recipes/abc", "/v1/recipes/1/2"} {
+		w = httptest.NewRecorder()
+		v1RecipeHandler(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != 400 {
+			t.Errorf("Expected status 400 for %s, got: %d", path, w.Code)
+		}
+	}
+	w = httptest.NewRecorder()
+	v1SearchHandler(w, httptest.NewRequest("GET", "/v1/search", nil))
+	if w.Code != 400 {
+		t.Errorf("Expected status 400 without q, got: %d", w.Code)
+	}
+}
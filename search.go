@@ -0,0 +1,253 @@
+package ck
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mswift42/goquery"
+)
+
+// SearchPageSize is the number of recipes chefkoch lists per result page.
+const SearchPageSize = 30
+
+// SearchPageCap limits how many upstream result pages a filtered search
+// may pull to fill one page of matches.
+var SearchPageCap = 5
+
+// searchFilter holds the optional /search filters. Zero values disable
+// the respective filter.
+type searchFilter struct {
+	difficulty map[string]bool
+	maxTime    int
+	minRating  float64
+	minVotes   int
+}
+
+// newSearchFilter reads the filter parameters difficulty (comma separated
+// list of simpel, normal and pfiffig), maxtime in minutes, minrating and
+// minvotes from r.
+func newSearchFilter(r *http.Request) (*searchFilter, error) {
+	f := &searchFilter{}
+	if d := r.FormValue("difficulty"); d != "" {
+		if err := f.setDifficulty(strings.Split(d, ",")); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if s := r.FormValue("maxtime"); s != "" {
+		if f.maxTime, err = strconv.Atoi(s); err != nil || f.maxTime < 1 {
+			return nil, &BadRequestError{"maxtime must be a positive number of minutes"}
+		}
+	}
+	if s := r.FormValue("minrating"); s != "" {
+		f.minRating, err = strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+		if err != nil || f.minRating < 0 || f.minRating > 5 {
+			return nil, &BadRequestError{"minrating must be between 0 and 5"}
+		}
+	}
+	if s := r.FormValue("minvotes"); s != "" {
+		if f.minVotes, err = strconv.Atoi(s); err != nil || f.minVotes < 0 {
+			return nil, &BadRequestError{"minvotes must be a non-negative number"}
+		}
+	}
+	return f, nil
+}
+
+func (f *searchFilter) setDifficulty(levels []string) error {
+	f.difficulty = make(map[string]bool)
+	for _, i := range levels {
+		i = strings.ToLower(strings.TrimSpace(i))
+		switch i {
+		case "simpel", "normal", "pfiffig":
+			f.difficulty[i] = true
+		default:
+			return &BadRequestError{"difficulty must be simpel, normal or pfiffig"}
+		}
+	}
+	return nil
+}
+
+func (f *searchFilter) active() bool {
+	return f.difficulty != nil || f.maxTime > 0 || f.minRating > 0 ||
+		f.minVotes > 0
+}
+
+func (f *searchFilter) match(r *Recipe) bool {
+	if f.difficulty != nil && !f.difficulty[r.Difficulty] {
+		return false
+	}
+	if f.maxTime > 0 && (r.PreptimeMinutes == 0 || r.PreptimeMinutes > f.maxTime) {
+		return false
+	}
+	if f.minRating > 0 {
+		rating, err := strconv.ParseFloat(r.Rating, 64)
+		if err != nil || rating < f.minRating {
+			return false
+		}
+	}
+	return r.Votes >= f.minVotes
+}
+
+// SearchResponse is the envelope returned by /search. Next and Prev are
+// cursors to pass back as the cursor parameter; they are omitted when
+// there is no such page. Filtered searches only offer Next, as their
+// pages do not line up with the upstream result pages.
+type SearchResponse struct {
+	Version  int       `json:"version"`
+	Query    string    `json:"query"`
+	Total    int       `json:"total"`
+	Page     int       `json:"page"`
+	PageSize int       `json:"pagesize"`
+	Next     string    `json:"next,omitempty"`
+	Prev     string    `json:"prev,omitempty"`
+	Results  []*Recipe `json:"results"`
+}
+
+// SearchResponseVersion is the current version of SearchResponse.
+const SearchResponseVersion = 1
+
+// searchCursor points at an upstream result offset. Skip is the number of
+// matching recipes on that page that were already returned.
+type searchCursor struct {
+	Offset int
+	Skip   int
+}
+
+func (c searchCursor) String() string {
+	if c.Skip == 0 {
+		return strconv.Itoa(c.Offset)
+	}
+	return strconv.Itoa(c.Offset) + "." + strconv.Itoa(c.Skip)
+}
+
+func parseCursor(s string) (searchCursor, error) {
+	var c searchCursor
+	split := strings.Split(s, ".")
+	if len(split) > 2 {
+		return c, &BadRequestError{"invalid cursor"}
+	}
+	var err error
+	if c.Offset, err = strconv.Atoi(split[0]); err != nil || c.Offset < 0 ||
+		c.Offset%SearchPageSize != 0 {
+		return c, &BadRequestError{"invalid cursor"}
+	}
+	if len(split) == 2 {
+		if c.Skip, err = strconv.Atoi(split[1]); err != nil || c.Skip < 0 {
+			return c, &BadRequestError{"invalid cursor"}
+		}
+	}
+	return c, nil
+}
+
+// searchRequestCursor reads the position of the requested page from r:
+// a cursor from an earlier response, a page number starting at 1 in
+// pagenum, or the raw chefkoch result offset (0, 30, 60, ...) in page.
+func searchRequestCursor(r *http.Request) (searchCursor, error) {
+	if c := r.FormValue("cursor"); c != "" {
+		return parseCursor(c)
+	}
+	if p := r.FormValue("pagenum"); p != "" {
+		page, err := strconv.Atoi(p)
+		if err != nil || page < 1 {
+			return searchCursor{}, &BadRequestError{"pagenum must be a positive number"}
+		}
+		return searchCursor{Offset: pageOffset(page)}, nil
+	}
+	if p := r.FormValue("page"); p != "" {
+		c, err := parseCursor(p)
+		if err != nil || c.Skip != 0 {
+			return searchCursor{}, &BadRequestError{"page must be a result offset such as 0 or " +
+				strconv.Itoa(SearchPageSize)}
+		}
+		return c, nil
+	}
+	return searchCursor{}, nil
+}
+
+var totalRegex = regexp.MustCompile(`^[\d.]+`)
+
+// searchTotal reads the number of hits from the heading of a search
+// result page, e.g. "5.922 bohnen Rezepte".
+func searchTotal(doc *goquery.Document) int {
+	heading := strings.TrimSpace(doc.Find("h1").First().Text())
+	total := strings.Replace(totalRegex.FindString(heading), ".", "", -1)
+	n, _ := strconv.Atoi(total)
+	return n
+}
+
+// searchRecipes returns one page of recipes for query starting at cursor.
+// With active filters it keeps fetching result pages until it has
+// SearchPageSize matches, runs out of results or reaches SearchPageCap
+// pages. If a later page fails, the matches so far are returned with a
+// cursor pointing at the failed page.
+func searchRecipes(ctx context.Context, query string, cursor searchCursor, f *searchFilter) (*SearchResponse, error) {
+	resp := &SearchResponse{Version: SearchResponseVersion, Query: query,
+		Page: cursor.Offset/SearchPageSize + 1, PageSize: SearchPageSize,
+		Results: []*Recipe{}}
+	pages := 1
+	if f.active() {
+		pages = SearchPageCap
+	}
+	offset, skip := cursor.Offset, cursor.Skip
+	var next *searchCursor
+	exhausted := false
+fetch:
+	for i := 0; i < pages; i++ {
+		doc, err := fetchDocument(ctx, queryUrl(query, strconv.Itoa(offset)), SearchTTL)
+		if err != nil && i == 0 {
+			return nil, err
+		}
+		if err != nil {
+			log.Printf("search %q: stopping at offset %d: %v", query, offset, err)
+			break
+		}
+		if i == 0 {
+			resp.Total = searchTotal(doc)
+		}
+		recipes := allRecipes(doc)
+		matched := 0
+		for _, r := range recipes {
+			if !f.match(r) {
+				continue
+			}
+			matched++
+			if matched <= skip {
+				continue
+			}
+			if len(resp.Results) == SearchPageSize {
+				next = &searchCursor{offset, matched - 1}
+				break fetch
+			}
+			resp.Results = append(resp.Results, r)
+		}
+		skip = 0
+		offset += SearchPageSize
+		if len(recipes) < SearchPageSize ||
+			(resp.Total > 0 && offset >= resp.Total) {
+			exhausted = true
+			break
+		}
+		if len(resp.Results) == SearchPageSize {
+			break
+		}
+	}
+	if next == nil && !exhausted {
+		next = &searchCursor{Offset: offset}
+	}
+	if next != nil {
+		resp.Next = next.String()
+	}
+	if !f.active() && cursor.Offset > 0 {
+		resp.Prev = searchCursor{Offset: cursor.Offset - SearchPageSize}.String()
+	}
+	return resp, nil
+}
+
+func searchResponseToJson(resp *SearchResponse) ([]byte, error) {
+	return json.Marshal(resp)
+}
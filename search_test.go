@@ -0,0 +1,105 @@
+package ck
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+var searchFilters = []struct {
+	query   string
+	matches int
+}{
+	{"", 30},
+	{"difficulty=simpel", 13},
+	{"difficulty=normal,pfiffig", 17},
+	{"maxtime=15", 8},
+	{"minrating=4.5", 5},
+	{"minrating=4,5&minvotes=100", 1},
+	{"difficulty=simpel&maxtime=20&minvotes=10", 10},
+}
+
+func TestSearchFilter(t *testing.T) {
+	recipes := allRecipes(detailDocument("testhtml/bohnen.html"))
+	if recipes[0].Votes != 189 {
+		t.Errorf("Expected votes to be 189, got: %d", recipes[0].Votes)
+	}
+	for _, i := range searchFilters {
+		r := httptest.NewRequest("GET", "/search?query=bohnen&"+i.query, nil)
+		f, err := newSearchFilter(r)
+		if err != nil {
+			t.Errorf("Expected error to be nil, got: %v", err)
+			continue
+		}
+		if f.active() != (i.query != "") {
+			t.Errorf("Expected filter %q to be active: %v", i.query, i.query != "")
+		}
+		matches := 0
+		for _, r := range recipes {
+			if f.match(r) {
+				matches++
+			}
+		}
+		if matches != i.matches {
+			t.Errorf("Expected %d matches for %q, got: %d", i.matches, i.query, matches)
+		}
+	}
+}
+
+func TestSearchFilterErrors(t *testing.T) {
+	for _, i := range []string{"difficulty=schwer", "maxtime=abc", "minrating=6",
+		"minvotes=-1"} {
+		r := httptest.NewRequest("GET", "/search?query=bohnen&"+i, nil)
+		if _, err := newSearchFilter(r); err == nil {
+			t.Errorf("Expected an error for %q", i)
+		}
+	}
+}
+
+func TestSearchTotal(t *testing.T) {
+	if total := searchTotal(detailDocument("testhtml/bohnen.html")); total != 5922 {
+		t.Errorf("Expected total to be 5922, got: %d", total)
+	}
+	if total := searchTotal(detailDocument("testhtml/sahne.html")); total != 75600 {
+		t.Errorf("Expected total to be 75600, got: %d", total)
+	}
+}
+
+var searchCursors = []struct {
+	query  string
+	cursor searchCursor
+	err    bool
+}{
+	{"", searchCursor{}, false},
+	{"pagenum=1", searchCursor{}, false},
+	{"pagenum=3", searchCursor{Offset: 60}, false},
+	{"page=0", searchCursor{}, false},
+	{"page=30", searchCursor{Offset: 30}, false},
+	{"pagenum=2&page=90", searchCursor{Offset: 30}, false},
+	{"cursor=90", searchCursor{Offset: 90}, false},
+	{"cursor=60.12&pagenum=1", searchCursor{Offset: 60, Skip: 12}, false},
+	{"pagenum=0", searchCursor{}, true},
+	{"page=45", searchCursor{}, true},
+	{"page=30.2", searchCursor{}, true},
+	{"cursor=45", searchCursor{}, true},
+	{"cursor=30.x", searchCursor{}, true},
+}
+
+func TestSearchRequestCursor(t *testing.T) {
+	for _, i := range searchCursors {
+		r := httptest.NewRequest("GET", "/search?query=bohnen&"+i.query, nil)
+		c, err := searchRequestCursor(r)
+		if (err != nil) != i.err {
+			t.Errorf("Expected error for %q to be %v, got: %v", i.query, i.err, err)
+			continue
+		}
+		if !i.err && c != i.cursor {
+			t.Errorf("Expected cursor for %q to be %v, got: %v", i.query, i.cursor, c)
+		}
+		if !i.err {
+			parsed, err := parseCursor(c.String())
+			if err != nil || parsed != c {
+				t.Errorf("Expected cursor %q to round trip, got: %v, %v", c, parsed, err)
+			}
+		}
+	}
+}
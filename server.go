@@ -0,0 +1,63 @@
+package ck
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// NewServeMux returns a mux serving all API routes. Responses allow
+// cross-origin requests from corsOrigin, e.g. "*"; an empty corsOrigin
+// sends no CORS headers.
+func NewServeMux(corsOrigin string) *http.ServeMux {
+	mux := http.NewServeMux()
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.Handle(pattern, cors(corsOrigin, h))
+	}
+	handle("/search", searchHandler)
+	handle("/recipedetail", detailHandler)
+	handle("/searchpages", bulkSearchHandler)
+	handle("/cachestats", cacheStatsHandler)
+	handle("/shoppinglist", shoppingListHandler)
+	handle("/v1/search", v1SearchHandler)
+	handle("/v1/recipes/", v1RecipeHandler)
+	handle("/v1/plans", plansHandler)
+	handle("/v1/plans/", planHandler)
+	return mux
+}
+
+// cors adds the CORS headers for origin to the responses of h and
+// answers preflight requests.
+func cors(origin string, h http.HandlerFunc) http.Handler {
+	if origin == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if origin != "*" {
+			w.Header().Add("Vary", "Origin")
+		}
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Request-Id")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h(w, r)
+	})
+}
+
+// ConfigureFromEnv sets up Store, Offline, Plans and DefaultFetcher from
+// the CK_STORE_DIR, CK_SEED_DIR, CK_OFFLINE, CK_PLAN_DIR, CK_RECORD_DIR
+// and CK_REPLAY environment variables.
+func ConfigureFromEnv() error {
+	if err := storeFromEnv(); err != nil {
+		return fmt.Errorf("disk store: %v", err)
+	}
+	if err := planStoreFromEnv(); err != nil {
+		return fmt.Errorf("plan store: %v", err)
+	}
+	if err := fetcherFromEnv(); err != nil {
+		return fmt.Errorf("recording fetcher: %v", err)
+	}
+	return nil
+}
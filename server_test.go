@@ -0,0 +1,44 @@
+package ck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewServeMux(t *testing.T) {
+	tests := []struct {
+		origin string
+		method string
+		path   string
+		status int
+	}{
+		{"*", "GET", "/search?pagenum=0", 400},
+		{"*", "GET", "/recipedetail?recipeurl=http://localhost/", 400},
+		{"*", "GET", "/searchpages?pages=100", 400},
+		{"*", "GET", "/cachestats", 200},
+		{"*", "GET", "/v1/search", 400},
+		{"*", "GET", "/v1/recipes/abc", 400},
+		{"*", "GET", "/unknown", 404},
+		{"https://example.com", "OPTIONS", "/v1/search", 204},
+		{"", "GET", "/cachestats", 200},
+	}
+	for _, i := range tests {
+		mux := NewServeMux(i.origin)
+		r := httptest.NewRequest(i.method, i.path, nil)
+		if i.method == "OPTIONS" {
+			r.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != i.status {
+			t.Errorf("Expected status %d for %s %s, got: %d", i.status, i.method, i.path, w.Code)
+		}
+		if i.status == http.StatusNotFound {
+			continue
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != i.origin {
+			t.Errorf("Expected CORS origin %q for %s, got: %q", i.origin, i.path, got)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package ck
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+func (rdd *RecipeDetailDocument) servings() string {
+	return strings.TrimSpace(rdd.doc.Find("#divisor").AttrOr("value", ""))
+}
+
+func (ld *ldRecipe) servings() string {
+	for _, i := range ldStrings(ld.RecipeYield, "") {
+		if fields := strings.Fields(i); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+// Scale rescales every parsed ingredient quantity of rd from its default
+// portion count to servings and rewrites the displayed amounts.
+// Ingredients without a quantity are left as they are.
+func (rd *RecipeDetail) Scale(servings int) {
+	if rd.Servings <= 0 || servings <= 0 || servings == rd.Servings {
+		return
+	}
+	factor := float64(servings) / float64(rd.Servings)
+	for _, i := range rd.Ingredients {
+		if i.Quantity == 0 {
+			continue
+		}
+		i.Quantity = roundKitchen(i.Quantity*factor, i.Unit)
+		if i.MaxQuantity != 0 {
+			i.MaxQuantity = roundKitchen(i.MaxQuantity*factor, i.Unit)
+		}
+		i.Amount = formatAmount(i.Quantity, i.MaxQuantity, i.Unit)
+	}
+	rd.Servings = servings
+}
+
+// roundKitchen rounds a scaled quantity to a step that can be measured
+// in a kitchen: whole pieces, quarter spoons, eighth litres and round
+// gram values.
+func roundKitchen(q float64, unit string) float64 {
+	var step, min float64
+	switch unit {
+	case "g", "ml":
+		switch {
+		case q < 10:
+			step = 1
+		case q < 100:
+			step = 5
+		case q < 1000:
+			step = 10
+		default:
+			step = 50
+		}
+		min = 1
+	case "kg", "l":
+		step, min = 0.125, 0.125
+		if q >= 1 {
+			step = 0.25
+		}
+	case "EL", "TL":
+		step, min = 0.25, 0.25
+	case "Prise", "Msp.":
+		step, min = 1, 1
+	case "":
+		// Plain counts such as eggs or onions.
+		step, min = 1, 1
+	default:
+		step, min = 0.5, 0.5
+	}
+	return math.Max(min, math.Round(q/step)*step)
+}
+
+var fractionGlyphs = []struct {
+	value float64
+	glyph string
+}{
+	{0.125, "⅛"},
+	{0.25, "¼"},
+	{1.0 / 3, "⅓"},
+	{0.5, "½"},
+	{2.0 / 3, "⅔"},
+	{0.75, "¾"},
+}
+
+// formatQuantity renders q the way chefkoch does, using fraction glyphs
+// for common fractions and a decimal comma otherwise.
+func formatQuantity(q float64) string {
+	whole, frac := math.Modf(q)
+	if frac < 0.001 {
+		return strconv.FormatFloat(whole, 'f', -1, 64)
+	}
+	for _, i := range fractionGlyphs {
+		if math.Abs(frac-i.value) < 0.001 {
+			if whole == 0 {
+				return i.glyph
+			}
+			return strconv.FormatFloat(whole, 'f', -1, 64) + i.glyph
+		}
+	}
+	s := strconv.FormatFloat(q, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return strings.Replace(s, ".", ",", 1)
+}
+
+func formatAmount(min, max float64, unit string) string {
+	amount := formatQuantity(min)
+	if max != 0 {
+		amount += "-" + formatQuantity(max)
+	}
+	if unit != "" {
+		amount += "\u00a0" + unit
+	}
+	return amount
+}
@@ -0,0 +1,81 @@
+package ck
+
+import "testing"
+
+func TestServings(t *testing.T) {
+	files := []struct {
+		file     string
+		servings int
+	}{
+		{"testhtml/gruene_bohnen_im_speckmantel.html", 4},
+		{"testhtml/schupfnudel.html", 2},
+		{"testhtml/gruene_bohnen_mit_kasseler.html", 4},
+	}
+	for _, i := range files {
+		rdd := &RecipeDetailDocument{detailDocument(i.file)}
+		if rd := rdd.newRecipeDetail(); rd.Servings != i.servings {
+			t.Errorf("Expected servings of %s to be %d, got: %d",
+				i.file, i.servings, rd.Servings)
+		}
+		if s := rdd.servings(); s != formatQuantity(float64(i.servings)) {
+			t.Errorf("Expected divisor of %s to be %d, got: %q",
+				i.file, i.servings, s)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	rdd := &RecipeDetailDocument{detailDocument("testhtml/schupfnudel.html")}
+	rd := rdd.newRecipeDetail()
+	rd.Scale(3)
+	want := []struct {
+		quantity float64
+		amount   string
+	}{
+		{750, "750\u00a0g"},
+		{300, "300\u00a0g"},
+		{380, "380\u00a0g"},
+		{0.25, "¼\u00a0l"},
+		{1.5, "1½\u00a0Becher"},
+		{6, "6\u00a0Scheibe/n"},
+		{0, "\u00a0n. B."},
+		{0, "\u00a0"},
+	}
+	if rd.Servings != 3 {
+		t.Errorf("Expected servings to be 3, got: %d", rd.Servings)
+	}
+	for ind, i := range rd.Ingredients {
+		if i.Quantity != want[ind].quantity {
+			t.Errorf("Expected quantity to be %v, got: %v",
+				want[ind].quantity, i.Quantity)
+		}
+		if i.Amount != want[ind].amount {
+			t.Errorf("Expected amount to be %q, got: %q", want[ind].amount, i.Amount)
+		}
+	}
+}
+
+var kitchenRoundings = []struct {
+	q    float64
+	unit string
+	want float64
+}{
+	{1.5, "", 2},
+	{0.5, "", 1},
+	{0.6, "TL", 0.5},
+	{0.1, "TL", 0.25},
+	{0.375, "l", 0.375},
+	{123, "g", 120},
+	{1234, "g", 1250},
+	{0.4, "Prise", 1},
+	{1.3, "Bund", 1.5},
+}
+
+func TestRoundKitchen(t *testing.T) {
+	for _, i := range kitchenRoundings {
+		if got := roundKitchen(i.q, i.unit); got != i.want {
+			t.Errorf("Expected %v %s to round to %v, got: %v",
+				i.q, i.unit, i.want, got)
+		}
+	}
+}
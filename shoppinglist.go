@@ -0,0 +1,504 @@
+package ck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"unicode"
+)
+
+// ShoppingListMaxRecipes limits the number of recipes of one
+// /shoppinglist request and the different recipes fetched for a plan.
+var ShoppingListMaxRecipes = 30
+
+// ShoppingListRecipe is a recipe of a shopping list. ID is a recipe id or
+// chefkoch URL; Servings zero keeps the servings of the recipe. In
+// responses Title and Servings are those of the fetched recipe.
+type ShoppingListRecipe struct {
+	ID       string `json:"id"`
+	Title    string `json:"title,omitempty"`
+	Servings int    `json:"servings,omitempty"`
+}
+
+// ShoppingListRequest is the body of POST /shoppinglist.
+type ShoppingListRequest struct {
+	Recipes []*ShoppingListRecipe `json:"recipes"`
+}
+
+// ShoppingList holds the merged ingredients of several recipes, grouped
+// by supermarket aisle.
+type ShoppingList struct {
+	Recipes []*ShoppingListRecipe `json:"recipes"`
+	Aisles  []*ShoppingAisle      `json:"aisles"`
+}
+
+// ShoppingAisle is a supermarket section and the items bought there,
+// sorted by name.
+type ShoppingAisle struct {
+	Name  string          `json:"name"`
+	Items []*ShoppingItem `json:"items"`
+}
+
+// ShoppingItem is an ingredient summed over all recipes using it. Weights
+// and volumes are converted to a common unit before they are added.
+// Recipes lists the ids of those recipes.
+type ShoppingItem struct {
+	Name        string   `json:"name"`
+	Amount      string   `json:"amount"`
+	Quantity    float64  `json:"quantity,omitempty"`
+	MaxQuantity float64  `json:"maxquantity,omitempty"`
+	Unit        string   `json:"unit,omitempty"`
+	Notes       []string `json:"notes,omitempty"`
+	Recipes     []string `json:"recipes"`
+}
+
+// Aisles in the order of a shopping list.
+const (
+	AisleProduce = "Produce"
+	AisleMeat    = "Meat & Fish"
+	AisleChilled = "Dairy & Chilled"
+	AisleFrozen  = "Frozen"
+	AisleBakery  = "Bakery"
+	AislePantry  = "Pantry"
+	AisleSpices  = "Spices & Oils"
+	AisleDrinks  = "Drinks"
+	AisleOther   = "Other"
+)
+
+var aisleOrder = []string{AisleProduce, AisleMeat, AisleChilled, AisleFrozen,
+	AisleBakery, AislePantry, AisleSpices, AisleDrinks, AisleOther}
+
+// aisleKeywords assigns ingredient names to aisles. The longest keyword
+// found in a name wins, so "Bohnenkraut" is a spice and "Fleischbrühe"
+// is not meat. Keywords shorter than three letters only match whole
+// words.
+var aisleKeywords = map[string][]string{
+	AisleProduce: {"apfel", "aubergine", "avocado", "banane", "basilikum", "beeren",
+		"birne", "blumenkohl", "bohnen", "brokkoli", "champignon", "dill", "erbsen",
+		"frühlingszwiebel", "gurke", "ingwer", "karotte", "kartoffel", "knoblauch",
+		"kohl", "kräuter", "kürbis", "lauch", "limette", "mais", "möhre", "orange",
+		"paprika", "petersilie", "pilze", "porree", "radieschen", "rucola", "salat",
+		"schalotte", "schnittlauch", "sellerie", "spargel", "spinat", "tomate",
+		"zitrone", "zucchini", "zwiebel"},
+	AisleMeat: {"bratwurst", "fisch", "fleisch", "garnele", "gulasch", "hackfleisch",
+		"hähnchen", "huhn", "kasseler", "lachs", "lamm", "pute", "rind", "salami",
+		"schinken", "schnitzel", "schwein", "speck", "thunfisch", "wurst"},
+	AisleChilled: {"butter", "crème fraîche", "creme fraiche", "ei", "eier", "frischkäse",
+		"gnocchi", "joghurt", "käse", "margarine", "milch", "mozzarella", "parmesan",
+		"quark", "sahne", "schmand", "schupfnudel", "tofu"},
+	AisleFrozen: {"tiefkühl", "rahmspinat"},
+	AisleBakery: {"baguette", "brot", "brötchen", "toastbrot", "semmelbrösel"},
+	AislePantry: {"backpulver", "brühe", "couscous", "essig", "fleischbrühe",
+		"gemüsebrühe", "hefe", "honig", "ketchup", "kichererbsen", "linsen", "mandeln",
+		"mehl", "nudeln", "nüsse", "passierte tomaten", "reis", "senf", "spaghetti",
+		"speisestärke", "tomatenmark", "zucker"},
+	AisleSpices: {"bohnenkraut", "chili", "curry", "gewürz", "kreuzkümmel", "kümmel",
+		"lorbeer", "majoran", "muskat", "olivenöl", "oregano", "paprikapulver",
+		"pfeffer", "rapsöl", "rosmarin", "salz", "sonnenblumenöl", "thymian",
+		"vanille", "zimt", "öl"},
+	AisleDrinks: {"bier", "saft", "wasser", "wein"},
+}
+
+// ingredientAisle returns the aisle of the ingredient called name.
+func ingredientAisle(name string) string {
+	name = strings.ToLower(name)
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	aisle, best := AisleOther, 0
+	for _, a := range aisleOrder {
+		for _, k := range aisleKeywords[a] {
+			n := len([]rune(k))
+			if n <= best {
+				continue
+			}
+			match := false
+			if n < 3 {
+				for _, w := range words {
+					match = match || w == k
+				}
+			} else {
+				match = strings.Contains(name, k)
+			}
+			if match {
+				aisle, best = a, n
+			}
+		}
+	}
+	return aisle
+}
+
+// metricUnits maps weights and volumes to their smallest unit and the
+// factor converting into it.
+var metricUnits = map[string]struct {
+	base   string
+	factor float64
+}{
+	"g":  {"g", 1},
+	"kg": {"g", 1000},
+	"ml": {"ml", 1},
+	"l":  {"ml", 1000},
+}
+
+// pluralMarkers are the optional plural endings chefkoch appends to
+// ingredient names, e.g. "Zwiebel(n)".
+var pluralMarkers = []string{"(n)", "(e)", "(s)", "(en)", "(er)", "(in)"}
+
+// ingredientKeys returns the keys under which name is merged with other
+// ingredients: "Tomate(n)" stands for "tomate" and "tomaten", "Tomaten"
+// for itself and the singulars "tomate" and "tomat" it may have been
+// derived from.
+func ingredientKeys(name string) []string {
+	key := strings.ToLower(normalizeSpace(name))
+	for _, i := range pluralMarkers {
+		if strings.HasSuffix(key, i) {
+			stem := strings.TrimSpace(strings.TrimSuffix(key, i))
+			return []string{stem, stem + i[1:len(i)-1]}
+		}
+	}
+	keys := []string{key}
+	for _, i := range pluralMarkers {
+		ending := i[1 : len(i)-1]
+		if len(key) > len(ending)+1 && strings.HasSuffix(key, ending) {
+			keys = append(keys, strings.TrimSuffix(key, ending))
+		}
+	}
+	return keys
+}
+
+type shoppingTotal struct {
+	item     *ShoppingItem
+	min, max float64
+	ranged   bool
+}
+
+// NewShoppingList merges the ingredients of recipes. Ingredients with the
+// same name are added up if their units are equal or convert into each
+// other, such as g and kg.
+func NewShoppingList(recipes []*RecipeDetail) *ShoppingList {
+	list := &ShoppingList{Recipes: []*ShoppingListRecipe{}, Aisles: []*ShoppingAisle{}}
+	totals := make(map[string]*shoppingTotal)
+	var order []*shoppingTotal
+	for _, rd := range recipes {
+		list.Recipes = append(list.Recipes, &ShoppingListRecipe{ID: rd.ID,
+			Title: rd.Title, Servings: rd.Servings})
+		for _, i := range rd.Ingredients {
+			name := normalizeSpace(i.Name)
+			if name == "" {
+				name = normalizeSpace(i.Ingredient)
+			}
+			if name == "" {
+				continue
+			}
+			unit, factor := i.Unit, 1.0
+			if m, ok := metricUnits[unit]; ok {
+				unit, factor = m.base, m.factor
+			}
+			keys := ingredientKeys(name)
+			var t *shoppingTotal
+			for _, k := range keys {
+				if t = totals[k+"|"+unit]; t != nil {
+					break
+				}
+			}
+			if t == nil {
+				t = &shoppingTotal{item: &ShoppingItem{Name: name, Unit: unit,
+					Recipes: []string{}}}
+				order = append(order, t)
+			}
+			for _, k := range keys {
+				if totals[k+"|"+unit] == nil {
+					totals[k+"|"+unit] = t
+				}
+			}
+			t.min += i.Quantity * factor
+			if i.MaxQuantity != 0 {
+				t.max += i.MaxQuantity * factor
+				t.ranged = true
+			} else {
+				t.max += i.Quantity * factor
+			}
+			if note := normalizeSpace(i.Note); note != "" && !containsString(t.item.Notes, note) {
+				t.item.Notes = append(t.item.Notes, note)
+			}
+			if rd.ID != "" && !containsString(t.item.Recipes, rd.ID) {
+				t.item.Recipes = append(t.item.Recipes, rd.ID)
+			}
+		}
+	}
+	aisles := make(map[string]*ShoppingAisle)
+	for _, t := range order {
+		t.finish()
+		a := ingredientAisle(t.item.Name)
+		if aisles[a] == nil {
+			aisles[a] = &ShoppingAisle{Name: a}
+		}
+		aisles[a].Items = append(aisles[a].Items, t.item)
+	}
+	for _, i := range aisleOrder {
+		if a := aisles[i]; a != nil {
+			sort.SliceStable(a.Items, func(x, y int) bool {
+				return strings.ToLower(a.Items[x].Name) < strings.ToLower(a.Items[y].Name)
+			})
+			list.Aisles = append(list.Aisles, a)
+		}
+	}
+	return list
+}
+
+// finish sets the quantity and amount of the item, switching to kg and l
+// from 1000 g and 1000 ml on.
+func (t *shoppingTotal) finish() {
+	it := t.item
+	if t.min == 0 {
+		return
+	}
+	max := 0.0
+	if t.ranged {
+		max = t.max
+	}
+	switch {
+	case it.Unit == "g" && t.min >= 1000:
+		it.Unit, t.min, max = "kg", t.min/1000, max/1000
+	case it.Unit == "ml" && t.min >= 1000:
+		it.Unit, t.min, max = "l", t.min/1000, max/1000
+	}
+	it.Quantity, it.MaxQuantity = t.min, max
+	it.Amount = formatAmount(t.min, max, it.Unit)
+}
+
+func containsString(list []string, s string) bool {
+	for _, i := range list {
+		if i == s {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchRecipes fetches the recipes concurrently and scales them to the
+// requested servings. Recipes that could not be fetched are nil and the
+// error of the first of them is returned.
+func fetchRecipes(ctx context.Context, recipes []*ShoppingListRecipe) ([]*RecipeDetail, error) {
+	details := make([]*RecipeDetail, len(recipes))
+	errs := make([]error, len(recipes))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < BulkSearchWorkers && w < len(recipes); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				recurl, err := recipeURL(recipes[n].ID)
+				if err == nil {
+					details[n], err = fetchRecipeDetail(ctx, recurl)
+				}
+				if err != nil {
+					errs[n] = err
+					continue
+				}
+				details[n].Scale(recipes[n].Servings)
+			}
+		}()
+	}
+	for n := range recipes {
+		jobs <- n
+	}
+	close(jobs)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return details, err
+		}
+	}
+	return details, nil
+}
+
+// fetchShoppingList fetches the recipes and merges their ingredients.
+func fetchShoppingList(ctx context.Context, recipes []*ShoppingListRecipe) (*ShoppingList, error) {
+	details, err := fetchRecipes(ctx, recipes)
+	if err != nil {
+		return nil, err
+	}
+	return NewShoppingList(details), nil
+}
+
+// validate checks the recipe ids and servings of the request.
+func (req *ShoppingListRequest) validate() error {
+	if len(req.Recipes) == 0 {
+		return &BadRequestError{"recipes is required"}
+	}
+	if len(req.Recipes) > ShoppingListMaxRecipes {
+		return &BadRequestError{"at most " + strconv.Itoa(ShoppingListMaxRecipes) +
+			" recipes are allowed"}
+	}
+	for _, i := range req.Recipes {
+		if i == nil {
+			return &BadRequestError{"recipes must not contain null"}
+		}
+		if _, err := recipeURL(i.ID); err != nil {
+			return err
+		}
+		if i.Servings < 0 {
+			return &BadRequestError{"servings must be a positive number"}
+		}
+	}
+	return nil
+}
+
+// WriteShoppingListText writes list as plain text.
+func WriteShoppingListText(w io.Writer, list *ShoppingList) error {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "Shopping list")
+	fmt.Fprintln(&buf, "=============")
+	fmt.Fprintln(&buf)
+	for _, i := range list.Recipes {
+		fmt.Fprintf(&buf, "- %s\n", shoppingListRecipeLine(i))
+	}
+	for _, a := range list.Aisles {
+		fmt.Fprintln(&buf)
+		fmt.Fprintln(&buf, a.Name)
+		tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+		for _, i := range a.Items {
+			fmt.Fprintf(tw, "  %s\t%s\n", i.Amount, shoppingItemText(i))
+		}
+		tw.Flush()
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteShoppingListMarkdown writes list as a Markdown checklist.
+func WriteShoppingListMarkdown(w io.Writer, list *ShoppingList) error {
+	md := markdownEscaper.Replace
+	var buf bytes.Buffer
+	fmt.Fprint(&buf, "# Shopping list\n\n")
+	for _, i := range list.Recipes {
+		fmt.Fprintf(&buf, "- %s\n", md(shoppingListRecipeLine(i)))
+	}
+	for _, a := range list.Aisles {
+		fmt.Fprintf(&buf, "\n## %s\n\n", md(a.Name))
+		for _, i := range a.Items {
+			line := strings.TrimSpace(i.Amount + " " + shoppingItemText(i))
+			fmt.Fprintf(&buf, "- [ ] %s\n", md(line))
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func shoppingListRecipeLine(r *ShoppingListRecipe) string {
+	line := r.Title
+	if line == "" {
+		line = r.ID
+	}
+	if r.Servings > 0 {
+		line += " (" + strconv.Itoa(r.Servings) + " servings)"
+	}
+	return line
+}
+
+func shoppingItemText(i *ShoppingItem) string {
+	if len(i.Notes) == 0 {
+		return i.Name
+	}
+	return i.Name + " (" + strings.Join(i.Notes, "; ") + ")"
+}
+
+func writeJSONShoppingList(w io.Writer, list *ShoppingList) error {
+	json, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(json)
+	return err
+}
+
+// shoppingListFormat is an output format of /shoppinglist.
+type shoppingListFormat struct {
+	names       []string
+	contentType string
+	write       func(io.Writer, *ShoppingList) error
+}
+
+var shoppingListFormats = []*shoppingListFormat{
+	{[]string{"json"}, "application/json; charset=utf-8", writeJSONShoppingList},
+	{[]string{"md", "markdown"}, "text/markdown; charset=utf-8", WriteShoppingListMarkdown},
+	{[]string{"txt", "text"}, "text/plain; charset=utf-8", WriteShoppingListText},
+}
+
+// requestShoppingListFormat returns the format named by the format
+// parameter of r or, without one, the format preferred by its Accept
+// header. JSON is the default.
+func requestShoppingListFormat(r *http.Request) (*shoppingListFormat, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		for _, f := range shoppingListFormats {
+			for _, i := range f.names {
+				if strings.EqualFold(name, i) {
+					return f, nil
+				}
+			}
+		}
+		return nil, &BadRequestError{"format must be json, md or txt"}
+	}
+	types := make([]string, len(shoppingListFormats))
+	for n, f := range shoppingListFormats {
+		types[n] = mediaType(f.contentType)
+	}
+	if n := acceptedMediaType(r.Header.Get("Accept"), types); n >= 0 {
+		return shoppingListFormats[n], nil
+	}
+	return shoppingListFormats[0], nil
+}
+
+// serveShoppingList writes list in format.
+func serveShoppingList(w http.ResponseWriter, r *http.Request, list *ShoppingList,
+	format *shoppingListFormat) {
+	var buf bytes.Buffer
+	if err := format.write(&buf, list); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Add("Vary", "Accept")
+	w.Write(buf.Bytes())
+}
+
+// shoppingListHandler serves POST /shoppinglist with a
+// ShoppingListRequest body.
+func shoppingListHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, r, &MethodNotAllowedError{r.Method, "POST"})
+		return
+	}
+	format, err := requestShoppingListFormat(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var req ShoppingListRequest
+	if err := decodeJSONBody(w, r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), UpstreamTimeout)
+	defer cancel()
+	list, err := fetchShoppingList(ctx, req.Recipes)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	serveShoppingList(w, r, list, format)
+}
@@ -0,0 +1,176 @@
+package ck
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func shoppingRecipe(id string, servings int, ingredients ...[2]string) *RecipeDetail {
+	rd := &RecipeDetail{ID: id, Title: "Rezept " + id, Servings: servings}
+	for _, i := range ingredients {
+		rd.Ingredients = append(rd.Ingredients, newIngredient(i[0], i[1]))
+	}
+	return rd
+}
+
+func TestNewShoppingList(t *testing.T) {
+	list := NewShoppingList([]*RecipeDetail{
+		shoppingRecipe("1", 4, [2]string{"500 g", "Bohnen, grüne"},
+			[2]string{"1-2", "Zwiebel(n)"}, [2]string{"600 ml", "Milch"},
+			[2]string{"", "Salz"}, [2]string{"2 EL", "Öl"},
+			[2]string{"2", "Ei(er)"}, [2]string{"3", "Tomate(n)"}),
+		shoppingRecipe("2", 2, [2]string{"0,5 kg", "Bohnen"},
+			[2]string{"1", "Zwiebel"}, [2]string{"½ l", "Milch"},
+			[2]string{"n. B.", "Salz"}, [2]string{"100 ml", "Öl"},
+			[2]string{"1 Pck.", "Speck, gewürfelt"},
+			[2]string{"4", "Eier"}, [2]string{"2", "Tomaten"}),
+	})
+	if len(list.Recipes) != 2 || list.Recipes[1].Title != "Rezept 2" {
+		t.Errorf("Expected both recipes to be listed, got: %+v", list.Recipes)
+	}
+	want := []struct {
+		aisle, name, amount string
+		recipes             int
+	}{
+		{AisleProduce, "Bohnen", "1 kg", 2},
+		{AisleProduce, "Tomate(n)", "5", 2},
+		{AisleProduce, "Zwiebel(n)", "2-3", 2},
+		{AisleMeat, "Speck", "1 Pck.", 1},
+		{AisleChilled, "Ei(er)", "6", 2},
+		{AisleChilled, "Milch", "1,1 l", 2},
+		{AisleSpices, "Salz", "", 2},
+		{AisleSpices, "Öl", "2 EL", 1},
+		{AisleSpices, "Öl", "100 ml", 1},
+	}
+	var got []*ShoppingItem
+	var aisles []string
+	for _, a := range list.Aisles {
+		for _, i := range a.Items {
+			got = append(got, i)
+			aisles = append(aisles, a.Name)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d items, got: %d", len(want), len(got))
+	}
+	for n, i := range want {
+		g := got[n]
+		if aisles[n] != i.aisle || g.Name != i.name ||
+			strings.Replace(g.Amount, "\u00a0", " ", -1) != i.amount || len(g.Recipes) != i.recipes {
+			t.Errorf("Expected %s %q %q from %d recipes, got: %s %q %q from %q", i.aisle,
+				i.amount, i.name, i.recipes, aisles[n], g.Amount, g.Name, g.Recipes)
+		}
+	}
+	if notes := got[6].Notes; len(notes) != 1 || notes[0] != "n. B." {
+		t.Errorf("Expected the note of Salz to be kept, got: %q", notes)
+	}
+}
+
+var ingredientAisles = []struct {
+	name  string
+	aisle string
+}{
+	{"Bohnen", AisleProduce},
+	{"Bohnenkraut", AisleSpices},
+	{"Fleischbrühe", AislePantry},
+	{"Schinken, gekochter", AisleMeat},
+	{"Crème fraîche", AisleChilled},
+	{"Ei(er)", AisleChilled},
+	{"Weizenmehl", AislePantry},
+	{"Olivenöl", AisleSpices},
+	{"Paprikapulver, edelsüß", AisleSpices},
+	{"Brezel", AisleOther},
+}
+
+func TestIngredientAisle(t *testing.T) {
+	for _, i := range ingredientAisles {
+		if got := ingredientAisle(i.name); got != i.aisle {
+			t.Errorf("Expected %s in aisle %s, got: %s", i.name, i.aisle, got)
+		}
+	}
+}
+
+func TestShoppingListHandler(t *testing.T) {
+	defer useReplayFetcher(t)()
+	body := `{"recipes": [{"id": "1171381223217983", "servings": 4},
+		{"id": "https://www.chefkoch.de/rezepte/2406611380140966/Gruene-Bohnen-mit-Speck.html"}]}`
+	w := httptest.NewRecorder()
+	shoppingListHandler(w, httptest.NewRequest("POST", "/shoppinglist", strings.NewReader(body)))
+	if w.Code != 200 || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Fatalf("Expected 200 with JSON, got: %d %s", w.Code, w.Body.String())
+	}
+	var list ShoppingList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Recipes) != 2 || list.Recipes[0].Servings != 4 || list.Recipes[1].Servings != 4 {
+		t.Errorf("Expected two recipes for 4, got: %+v", list.Recipes)
+	}
+	var bohnen *ShoppingItem
+	for _, a := range list.Aisles {
+		for _, i := range a.Items {
+			if i.Name == "Bohnen" {
+				bohnen = i
+			}
+		}
+	}
+	if bohnen == nil || bohnen.Quantity != 1 || bohnen.Unit != "kg" || len(bohnen.Recipes) != 2 {
+		t.Errorf("Expected 1 kg Bohnen from both recipes, got: %+v", bohnen)
+	}
+
+	tests := []struct {
+		query    string
+		accept   string
+		contains string
+	}{
+		{"?format=md", "", "\n## Produce\n\n- [ ] 1 kg Bohnen ("},
+		{"", "text/markdown", "- [ ] 1 kg Bohnen ("},
+		{"?format=txt", "", "Shopping list\n=============\n\n- Schupfnudel - Bohnen - Pfanne (4 servings)\n"},
+	}
+	for _, i := range tests {
+		w = httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/shoppinglist"+i.query, strings.NewReader(body))
+		if i.accept != "" {
+			r.Header.Set("Accept", i.accept)
+		}
+		shoppingListHandler(w, r)
+		out := strings.Replace(w.Body.String(), "\u00a0", " ", -1)
+		if w.Code != 200 || !strings.Contains(out, i.contains) {
+			t.Errorf("Expected %q %q to contain %q, got: %d\n%s", i.query, i.accept,
+				i.contains, w.Code, out)
+		}
+	}
+}
+
+func TestShoppingListHandlerErrors(t *testing.T) {
+	defer useReplayFetcher(t)()
+	tests := []struct {
+		method string
+		query  string
+		body   string
+		status int
+	}{
+		{"GET", "", "", 405},
+		{"POST", "", "{", 400},
+		{"POST", "", `{"recipes": []}`, 400},
+		{"POST", "", `{"recipes": [{"id": "abc"}]}`, 400},
+		{"POST", "", `{"recipes": [{"id": "1171381223217983", "servings": -1}]}`, 400},
+		{"POST", "?format=pdf", `{"recipes": [{"id": "1171381223217983"}]}`, 400},
+		{"POST", "", `{"recipes": [{"id": "1171381223217983"}, {"id": "1"}]}`, 404},
+	}
+	for _, i := range tests {
+		w := httptest.NewRecorder()
+		shoppingListHandler(w, httptest.NewRequest(i.method, "/shoppinglist"+i.query,
+			strings.NewReader(i.body)))
+		if w.Code != i.status {
+			t.Errorf("Expected status %d for %s %q, got: %d", i.status, i.method, i.body, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	shoppingListHandler(w, httptest.NewRequest("GET", "/shoppinglist", nil))
+	if w.Header().Get("Allow") != "POST" {
+		t.Errorf("Expected Allow: POST, got: %q", w.Header().Get("Allow"))
+	}
+}
@@ -0,0 +1,137 @@
+package ck
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RecipeStep is one paragraph of the method together with the timers
+// and oven temperatures it mentions.
+type RecipeStep struct {
+	Text         string             `json:"text"`
+	Timers       []*StepTimer       `json:"timers,omitempty"`
+	Temperatures []*StepTemperature `json:"temperatures,omitempty"`
+}
+
+// StepTimer is a duration such as "10 Min. kochen" or the range
+// "10-20 Min.", in which case MaxMinutes is set.
+type StepTimer struct {
+	Text       string `json:"text"`
+	Minutes    int    `json:"minutes"`
+	MaxMinutes int    `json:"maxminutes,omitempty"`
+}
+
+// StepTemperature is an oven setting such as "180 °C - 200 °C" or
+// "Umluft". Celsius is zero when only the mode is given.
+type StepTemperature struct {
+	Text       string `json:"text"`
+	Celsius    int    `json:"celsius,omitempty"`
+	MaxCelsius int    `json:"maxcelsius,omitempty"`
+	Mode       string `json:"mode,omitempty"`
+}
+
+// ovenMinCelsius and ovenMaxCelsius bound the temperatures taken for oven
+// or stove settings.
+const (
+	ovenMinCelsius = 50
+	ovenMaxCelsius = 300
+)
+
+var (
+	timerRegex       = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)(?:\s*(?:-|–|bis)\s*(\d+(?:[.,]\d+)?))?\s*(stunden?|std\.?|h|minuten?|min\.?)`)
+	temperatureRegex = regexp.MustCompile(`(\d{2,3})\s*(?:°\s*C?|Grad)?(?:\s*(?:-|–|bis)\s*(\d{2,3}))?\s*(?:°\s*C?|Grad)`)
+	ovenModes        = []struct {
+		regex *regexp.Regexp
+		mode  string
+	}{
+		{regexp.MustCompile(`(?i)umluft|heißluft`), "Umluft"},
+		{regexp.MustCompile(`(?i)ober-\s*(?:/|und)\s*unterhitze`), "Ober-/Unterhitze"},
+		{regexp.MustCompile(`(?i)gas(?:stufe)?\s*\d`), "Gas"},
+	}
+)
+
+// parseSteps splits the method into its paragraphs, dropping empty lines,
+// and extracts timers and temperatures from each.
+func parseSteps(method string) []*RecipeStep {
+	var steps []*RecipeStep
+	for _, line := range strings.Split(method, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		steps = append(steps, &RecipeStep{Text: line, Timers: stepTimers(line),
+			Temperatures: stepTemperatures(line)})
+	}
+	return steps
+}
+
+// stepTimers finds the timers in text. An hour followed by minutes, as in
+// "1 Std. 30 Min.", is one timer.
+func stepTimers(text string) []*StepTimer {
+	var timers []*StepTimer
+	start, end, hours := 0, 0, false
+	for _, loc := range timerRegex.FindAllStringSubmatchIndex(text, -1) {
+		if letterAt(text, loc[1]) {
+			continue
+		}
+		group := func(n int) string {
+			if loc[2*n] < 0 {
+				return ""
+			}
+			return text[loc[2*n]:loc[2*n+1]]
+		}
+		unit := 1.0
+		if u := strings.ToLower(group(3)); strings.HasPrefix(u, "st") || u == "h" {
+			unit = 60
+		}
+		between := strings.TrimSpace(text[end:loc[0]])
+		if hours && unit == 1 && group(2) == "" && (between == "" || between == "und") {
+			last := timers[len(timers)-1]
+			last.Text = text[start:loc[1]]
+			last.Minutes += stepMinutes(group(1), unit)
+			hours = false
+			end = loc[1]
+			continue
+		}
+		timer := &StepTimer{Text: group(0), Minutes: stepMinutes(group(1), unit)}
+		if group(2) != "" {
+			timer.MaxMinutes = stepMinutes(group(2), unit)
+		}
+		timers = append(timers, timer)
+		start, end, hours = loc[0], loc[1], unit == 60 && group(2) == ""
+	}
+	return timers
+}
+
+func stepMinutes(s string, unit float64) int {
+	n, _ := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	return int(n * unit)
+}
+
+func stepTemperatures(text string) []*StepTemperature {
+	mode := ""
+	for _, i := range ovenModes {
+		if i.regex.MatchString(text) {
+			mode = i.mode
+			break
+		}
+	}
+	var temps []*StepTemperature
+	for _, m := range temperatureRegex.FindAllStringSubmatch(text, -1) {
+		temp := &StepTemperature{Text: m[0], Mode: mode}
+		temp.Celsius, _ = strconv.Atoi(m[1])
+		// "bei 12 Grad abkühlen" is a room or fridge temperature.
+		if temp.Celsius < ovenMinCelsius || temp.Celsius > ovenMaxCelsius {
+			continue
+		}
+		if m[2] != "" {
+			temp.MaxCelsius, _ = strconv.Atoi(m[2])
+		}
+		temps = append(temps, temp)
+	}
+	if len(temps) == 0 && mode != "" {
+		temps = append(temps, &StepTemperature{Text: mode, Mode: mode})
+	}
+	return temps
+}
@@ -0,0 +1,90 @@
+package ck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSteps(t *testing.T) {
+	rdd := &RecipeDetailDocument{detailDocument("testhtml/gruene_bohnen_im_speckmantel.html")}
+	steps := rdd.newRecipeDetail().Steps
+	if len(steps) != 3 {
+		t.Fatalf("Expected 3 steps, got: %d", len(steps))
+	}
+	if steps[0].Text != "Bohnen waschen und die Spitzen abschneiden." {
+		t.Errorf("Expected first step to be %q, got: %q",
+			"Bohnen waschen und die Spitzen abschneiden.", steps[0].Text)
+	}
+	wantTimers := []*StepTimer{{Text: "10 Min.", Minutes: 10}}
+	if !reflect.DeepEqual(steps[1].Timers, wantTimers) {
+		t.Errorf("Expected timers to be %+v, got: %+v", wantTimers[0], steps[1].Timers)
+	}
+	wantTemps := []*StepTemperature{{Text: "180 °C - 200 °C", Celsius: 180, MaxCelsius: 200}}
+	if !reflect.DeepEqual(steps[2].Temperatures, wantTemps) {
+		t.Errorf("Expected temperatures to be %+v, got: %+v", wantTemps[0],
+			steps[2].Temperatures)
+	}
+	if len(steps[2].Timers) != 1 || steps[2].Timers[0].Minutes != 5 {
+		t.Errorf("Expected a 5 minute timer, got: %+v", steps[2].Timers)
+	}
+}
+
+var stepTexts = []struct {
+	text   string
+	timers []*StepTimer
+	temps  []*StepTemperature
+}{
+	{
+		"pfeffern und 10-20 Min. bei kleiner Hitze ziehen lassen",
+		[]*StepTimer{{Text: "10-20 Min.", Minutes: 10, MaxMinutes: 20}},
+		nil,
+	},
+	{
+		"Im vorgeheizten Backofen bei 200 °C Umluft ca. 1 Std. backen.",
+		[]*StepTimer{{Text: "1 Std.", Minutes: 60}},
+		[]*StepTemperature{{Text: "200 °C", Celsius: 200, Mode: "Umluft"}},
+	},
+	{
+		"Die Brühe 10 min kochen, dann 1 Std ziehen lassen.",
+		[]*StepTimer{{Text: "10 min", Minutes: 10}, {Text: "1 Std", Minutes: 60}},
+		nil,
+	},
+	{
+		"Das Fleisch 2 h marinieren und 1 Stunde ruhen lassen.",
+		[]*StepTimer{{Text: "2 h", Minutes: 120}, {Text: "1 Stunde", Minutes: 60}},
+		nil,
+	},
+	{
+		"Bei 160 Grad 1 Std. 30 Min. schmoren, 2 Hähnchenschenkel nach 1 Stunde und 15 Minuten dazugeben.",
+		[]*StepTimer{{Text: "1 Std. 30 Min.", Minutes: 90},
+			{Text: "1 Stunde und 15 Minuten", Minutes: 75}},
+		[]*StepTemperature{{Text: "160 Grad", Celsius: 160}},
+	},
+	{
+		"Den Ofen auf 175-180 Grad Ober-/Unterhitze vorheizen.",
+		nil,
+		[]*StepTemperature{{Text: "175-180 Grad", Celsius: 175, MaxCelsius: 180,
+			Mode: "Ober-/Unterhitze"}},
+	},
+	{
+		"Den Teig bei 12 Grad abkühlen lassen und über Nacht bei 4 °C ruhen lassen.",
+		nil,
+		nil,
+	},
+	{
+		"Auf Umluft umstellen.",
+		nil,
+		[]*StepTemperature{{Text: "Umluft", Mode: "Umluft"}},
+	},
+}
+
+func TestStepTimersAndTemperatures(t *testing.T) {
+	for _, i := range stepTexts {
+		if timers := stepTimers(i.text); !reflect.DeepEqual(timers, i.timers) {
+			t.Errorf("Expected timers of %q to be %+v, got: %+v", i.text, i.timers, timers)
+		}
+		if temps := stepTemperatures(i.text); !reflect.DeepEqual(temps, i.temps) {
+			t.Errorf("Expected temperatures of %q to be %+v, got: %+v", i.text, i.temps, temps)
+		}
+	}
+}
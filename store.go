@@ -0,0 +1,176 @@
+package ck
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mswift42/goquery"
+)
+
+// NotCachedError is returned in offline mode for pages missing from the
+// DiskStore.
+type NotCachedError struct {
+	URL string
+}
+
+func (e *NotCachedError) Error() string {
+	return "not cached: " + e.URL
+}
+
+// StoredPage is an upstream page as kept by DiskStore.
+type StoredPage struct {
+	URL        string    `json:"url"`
+	Fetched    time.Time `json:"fetched"`
+	StatusCode int       `json:"status,omitempty"`
+	Body       string    `json:"body"`
+}
+
+// DiskStore keeps fetched pages as JSON files in a directory, one file
+// per canonical URL.
+type DiskStore struct {
+	dir string
+}
+
+// NewDiskStore opens the store in dir, creating the directory if needed.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DiskStore{dir}, nil
+}
+
+// canonicalURL drops the fragment and query of a chefkoch URL and forces
+// https on www.chefkoch.de, so that "…/Rezepte.html#more2" and the
+// canonical link of the page map to the same entry. Recipe pages are
+// keyed by their ID alone, as "/rezepte/<id>/" and the slug URL of the
+// canonical link serve the same recipe.
+func canonicalURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return raw
+	}
+	if u.Host == "chefkoch.de" || u.Host == "www.chefkoch.de" || u.Host == "" {
+		u.Scheme, u.Host = "https", "www.chefkoch.de"
+		if m := recipePathRegex.FindStringSubmatch(u.Path); m != nil {
+			return recipeIDURL(m[1])
+		}
+	}
+	u.Fragment, u.RawQuery = "", ""
+	return u.String()
+}
+
+func (s *DiskStore) path(rawurl string) string {
+	sum := sha1.Sum([]byte(canonicalURL(rawurl)))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the stored page for rawurl or a *NotCachedError.
+func (s *DiskStore) Get(rawurl string) (*StoredPage, error) {
+	data, err := ioutil.ReadFile(s.path(rawurl))
+	if os.IsNotExist(err) {
+		return nil, &NotCachedError{canonicalURL(rawurl)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var page StoredPage
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Put stores body as the page for rawurl, fetched at the given time.
+func (s *DiskStore) Put(rawurl string, body []byte, fetched time.Time) error {
+	return s.put(&StoredPage{URL: canonicalURL(rawurl), Fetched: fetched,
+		Body: string(body)})
+}
+
+func (s *DiskStore) put(page *StoredPage) error {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+	// Each write gets its own temporary file so concurrent puts of the same
+	// page cannot interleave; the rename makes the last one win.
+	tmp, err := ioutil.TempFile(s.dir, "page-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(page.URL))
+}
+
+// Seed loads saved chefkoch pages such as those in testhtml/ into the
+// store, keyed by their canonical link. It returns the number of pages
+// stored.
+func (s *DiskStore) Seed(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, i := range files {
+		body, err := ioutil.ReadFile(i)
+		if err != nil {
+			return count, err
+		}
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+		if err != nil {
+			return count, err
+		}
+		canonical := doc.Find(`link[rel="canonical"]`).AttrOr("href", "")
+		if canonical == "" {
+			continue
+		}
+		info, err := os.Stat(i)
+		if err != nil {
+			return count, err
+		}
+		if err := s.Put(canonical, body, info.ModTime()); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// storeFromEnv sets up Store and Offline from CK_STORE_DIR, CK_SEED_DIR
+// and CK_OFFLINE.
+func storeFromEnv() error {
+	dir := os.Getenv("CK_STORE_DIR")
+	if dir == "" {
+		return nil
+	}
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		return err
+	}
+	if seed := os.Getenv("CK_SEED_DIR"); seed != "" {
+		if _, err := store.Seed(seed); err != nil {
+			return err
+		}
+	}
+	Store = store
+	Offline = os.Getenv("CK_OFFLINE") != ""
+	return nil
+}
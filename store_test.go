@@ -0,0 +1,162 @@
+package ck
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// seededStore returns a store seeded from testhtml/ and a function
+// removing it again.
+func seededStore(t *testing.T) (*DiskStore, func()) {
+	dir, err := ioutil.TempDir("", "ckstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := store.Seed("testhtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 6 {
+		t.Errorf("Expected 6 seeded pages, got: %d", count)
+	}
+	return store, func() { os.RemoveAll(dir) }
+}
+
+// useSeededStore switches the package to offline mode backed by a store
+// seeded from testhtml/ and returns a function restoring the defaults.
+func useSeededStore(t *testing.T) func() {
+	store, remove := seededStore(t)
+	oldStore, oldOffline, oldCache := Store, Offline, PageCache
+	Store, Offline, PageCache = store, true, NewLRUCache(10)
+	return func() {
+		Store, Offline, PageCache = oldStore, oldOffline, oldCache
+		remove()
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	for _, i := range []struct{ url, want string }{
+		{queryUrl("bohnen", "0"), "https://www.chefkoch.de/rs/s0/bohnen/Rezepte.html"},
+		{queryUrl("a/b?c", "0"), "https://www.chefkoch.de/rs/s0/a%2Fb%3Fc/Rezepte.html"},
+		{"http://chefkoch.de/rezepte/1/a.html?x=1", "https://www.chefkoch.de/rezepte/1/"},
+		{schupfnudelURL, "https://www.chefkoch.de/rezepte/1171381223217983/"},
+		{"https://www.chefkoch.de/rezepte/1171381223217983", "https://www.chefkoch.de/rezepte/1171381223217983/"},
+		{"https://www.chefkoch.de/rezepte/drucken/1/a.html", "https://www.chefkoch.de/rezepte/drucken/1/a.html"},
+	} {
+		if got := canonicalURL(i.url); got != i.want {
+			t.Errorf("Expected canonical url to be %q, got: %q", i.want, got)
+		}
+	}
+}
+
+func TestDiskStoreConcurrentPut(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ckstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.Put(schupfnudelURL, []byte("<html></html>"), time.Now()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if page, err := store.Get(schupfnudelURL); err != nil || page.Body != "<html></html>" {
+		t.Errorf("Expected the stored page, got: %+v %v", page, err)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(tmp) != 0 {
+		t.Errorf("Expected no temporary files to be left, got: %q", tmp)
+	}
+}
+
+func TestOfflineFetch(t *testing.T) {
+	defer useSeededStore(t)()
+	doc, err := fetchDocument(context.Background(), queryUrl("bohnen", "0"), SearchTTL)
+	if err != nil {
+		t.Fatal("Expected error to be nil, got: ", err)
+	}
+	if len(allRecipes(doc)) != 30 {
+		t.Errorf("Expected 30 recipes, got: %d", len(allRecipes(doc)))
+	}
+	_, err = fetchPage(context.Background(), queryUrl("rotwein", "0"), SearchTTL)
+	if _, ok := err.(*NotCachedError); !ok {
+		t.Errorf("Expected a NotCachedError, got: %v", err)
+	}
+	corrupt := queryUrl("rotwein", "30")
+	if err := ioutil.WriteFile(Store.path(corrupt), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = fetchPage(context.Background(), corrupt, SearchTTL)
+	if _, ok := err.(*NotCachedError); !ok {
+		t.Errorf("Expected a NotCachedError for a corrupt entry, got: %v", err)
+	}
+}
+
+func TestOfflineDetailHandler(t *testing.T) {
+	defer useSeededStore(t)()
+	w := httptest.NewRecorder()
+	detailHandler(w, httptest.NewRequest("GET",
+		"/recipedetail?recipeurl="+schupfnudelURL, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", w.Code)
+	}
+	var rd RecipeDetail
+	if err := json.Unmarshal(w.Body.Bytes(), &rd); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Title != schupfnudel.title {
+		t.Errorf("Expected title to be %q, got: %q", schupfnudel.title, rd.Title)
+	}
+	w = httptest.NewRecorder()
+	detailHandler(w, httptest.NewRequest("GET",
+		"/recipedetail?recipeurl=https://www.chefkoch.de/rezepte/1/x.html", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got: %d", w.Code)
+	}
+}
+
+func TestOfflineRecipeIDRoutes(t *testing.T) {
+	defer useSeededStore(t)()
+	for _, path := range []string{
+		"/v1/recipes/1171381223217983",
+		"/recipedetail?recipeurl=1171381223217983",
+		"/recipedetail?recipeurl=" + schupfnudelURL,
+	} {
+		w := httptest.NewRecorder()
+		NewServeMux("").ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 for %s, got: %d %s", path, w.Code, w.Body.String())
+			continue
+		}
+		var rd RecipeDetail
+		if err := json.Unmarshal(w.Body.Bytes(), &rd); err != nil {
+			t.Fatal(err)
+		}
+		if rd.Title != schupfnudel.title {
+			t.Errorf("Expected title of %s to be %q, got: %q", path, schupfnudel.title, rd.Title)
+		}
+	}
+}
+
+const schupfnudelURL = "https://www.chefkoch.de/rezepte/1171381223217983/Schupfnudel-Bohnen-Pfanne.html"